@@ -0,0 +1,173 @@
+package main
+
+import (
+  "bytes"
+  "flag"
+  "fmt"
+  "github.com/seanpringle/prose/prose"
+  "image/png"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+)
+
+// Headless subcommands work on documents without touching SDL, for use
+// from scripts and build servers:
+//
+//   prose export [--format md] in.prose out.md
+//   prose import in.txt out.prose
+//   prose stats in.prose
+//   prose snapshot [--width 800] [--height 600] [--paragraph n] in.prose out.png
+type cliCommand struct {
+  usage string
+  run   func(args []string) error
+}
+
+var commands map[string]cliCommand
+
+func init() {
+  commands = map[string]cliCommand{
+    "export":   {"export [--format fmt] in.prose out", cliExport},
+    "import":   {"import in.txt out.prose", cliImport},
+    "stats":    {"stats in.prose", cliStats},
+    "snapshot": {"snapshot [--width w] [--height h] [--paragraph n] in.prose out.png", cliSnapshot},
+  }
+}
+
+// usageError marks bad command lines, which exit with status 2 rather
+// than 1.
+type usageError string
+
+func (self usageError) Error() string {
+  return "usage: prose " + string(self)
+}
+
+// headless runs a subcommand and returns the process exit status.
+func headless(name string, args []string) int {
+  cmd := commands[name]
+  if err := cmd.run(args); err != nil {
+    fmt.Fprintln(os.Stderr, err)
+    if _, ok := err.(usageError); ok {
+      return 2
+    }
+    return 1
+  }
+  return 0
+}
+
+func cliFlags(name string) *flag.FlagSet {
+  flags := flag.NewFlagSet(name, flag.ContinueOnError)
+  flags.SetOutput(ioutil.Discard)
+  return flags
+}
+
+func known(names []string, name string) bool {
+  for _, n := range names {
+    if n == name {
+      return true
+    }
+  }
+  return false
+}
+
+func cliExport(args []string) error {
+  usage := usageError(commands["export"].usage)
+
+  flags := cliFlags("export")
+  format := flags.String("format", "", "output format: "+strings.Join(prose.ExportFormats(), ", "))
+  if flags.Parse(args) != nil || flags.NArg() != 2 {
+    return usage
+  }
+
+  if *format == "" {
+    *format = strings.TrimPrefix(filepath.Ext(flags.Arg(1)), ".")
+  }
+  if !known(prose.ExportFormats(), *format) {
+    return fmt.Errorf("unknown export format %q, want one of: %s", *format, strings.Join(prose.ExportFormats(), ", "))
+  }
+
+  doc, err := prose.Open(flags.Arg(0), opts.doc())
+  if err != nil {
+    return err
+  }
+  return doc.Export(*format, flags.Arg(1))
+}
+
+func cliImport(args []string) error {
+  if len(args) != 2 {
+    return usageError(commands["import"].usage)
+  }
+
+  doc := prose.Create(args[1], opts.doc())
+
+  if err := doc.Import(args[0]); err != nil {
+    return err
+  }
+  // write the file even if there was nothing to import
+  return doc.ReSave(args[1])
+}
+
+func cliStats(args []string) error {
+  if len(args) != 1 {
+    return usageError(commands["stats"].usage)
+  }
+
+  doc, err := prose.Open(args[0], opts.doc())
+  if err != nil {
+    return err
+  }
+
+  paras := doc.Paragraphs()
+  headings := 0
+  words := 0
+  chars := 0
+
+  for _, para := range paras {
+    if para.Style() == prose.Heading {
+      headings++
+    }
+    for _, word := range para.Words() {
+      words++
+      chars += prose.Clusters(word.Display())
+    }
+  }
+
+  fmt.Printf("paragraphs %d\n", len(paras))
+  fmt.Printf("headings %d\n", headings)
+  fmt.Printf("words %d\n", words)
+  fmt.Printf("characters %d\n", chars)
+  fmt.Printf("variables %d\n", len(doc.Variables()))
+  return nil
+}
+
+// cliSnapshot renders the editor's view of a document to PNG, centred
+// on a chosen paragraph, without opening a window.
+func cliSnapshot(args []string) error {
+  usage := usageError(commands["snapshot"].usage)
+
+  flags := cliFlags("snapshot")
+  width := flags.Int("width", 800, "image width")
+  height := flags.Int("height", 600, "image height")
+  paragraph := flags.Int("paragraph", 1, "paragraph to centre on")
+  if flags.Parse(args) != nil || flags.NArg() != 2 || *width < 1 || *height < 1 {
+    return usage
+  }
+
+  doc, err := prose.Open(flags.Arg(0), opts.doc())
+  if err != nil {
+    return err
+  }
+
+  paras := doc.All()
+  if *paragraph < 1 || *paragraph > len(paras) {
+    return fmt.Errorf("paragraph %d out of range 1-%d", *paragraph, len(paras))
+  }
+  doc.Seek(paras[*paragraph-1])
+
+  var buf bytes.Buffer
+  if err := png.Encode(&buf, snapshot(doc, *width, *height)); err != nil {
+    return err
+  }
+  return ioutil.WriteFile(flags.Arg(1), buf.Bytes(), 0644)
+}
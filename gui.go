@@ -1,37 +1,56 @@
 package main
 
 import (
-  "fmt"
+  "bytes"
   "github.com/seanpringle/go-sdl2/sdl"
   "github.com/seanpringle/gostuff/box"
   "github.com/seanpringle/gostuff/history"
   "github.com/seanpringle/gostuff/jobqueue"
   "github.com/seanpringle/gostuff/menu"
   "github.com/seanpringle/gostuff/text"
+  "github.com/seanpringle/prose/prose"
   "image"
   "image/color"
   "strings"
   "time"
-  "unsafe"
 )
 
 var (
   Dark  color.Color = color.RGBA{100, 100, 100, 255}
   Light color.Color = color.RGBA{200, 200, 200, 255}
+  Alert color.Color = color.RGBA{220, 100, 100, 255}
+  Shade color.Color = color.RGBA{60, 60, 90, 255}
 )
 
+const noticeTimeout = 10 * time.Second
+
+// repeatable keys act again while held. The rest toggle flags or open
+// menus, which would only flicker.
+var repeatable = map[sdl.Keycode]bool{
+  sdl.K_UP:        true,
+  sdl.K_DOWN:      true,
+  sdl.K_LEFT:      true,
+  sdl.K_RIGHT:     true,
+  sdl.K_HOME:      true,
+  sdl.K_END:       true,
+  sdl.K_BACKSPACE: true,
+  sdl.K_DELETE:    true,
+}
+
 type guiAPI struct {
-  job  func(func())
-  jobs func()
-  tick func()
-  exit func()
-  box  func() box.Box
-  done func() bool
+  job    func(func())
+  jobs   func()
+  tick   func(time.Time)
+  exit   func()
+  box    func() box.Box
+  done   func() bool
+  notify func(string)
 }
 
 const (
   BackGround int = iota
   Document
+  Overlay
   Layers
 )
 
@@ -44,8 +63,24 @@ type sprite struct {
   cache bool
 }
 
-func boxSDL(self box.Box) sdl.Rect {
-  return sdl.Rect{int32(self.X), int32(self.Y), int32(self.W), int32(self.H)}
+// cstr converts SDL's NUL terminated text event buffers.
+func cstr(buf []byte) string {
+  if n := bytes.IndexByte(buf, 0); n >= 0 {
+    buf = buf[:n]
+  }
+  return string(buf)
+}
+
+// overlay places a whole image at x, y above the document.
+func overlay(rgba *image.RGBA, x, y int, cache bool) *sprite {
+  rect := rgba.Bounds()
+  return &sprite{
+    rgba:  rgba,
+    layer: Overlay,
+    src:   box.Box{0, 0, rect.Dx(), rect.Dy()},
+    dst:   box.Box{x, y, rect.Dx(), rect.Dy()},
+    cache: cache,
+  }
 }
 
 func newGUI() *guiAPI {
@@ -63,6 +98,7 @@ func newGUI() *guiAPI {
   }
 
   quit := false
+  forceQuit := false
 
   self.done = func() bool {
     return quit
@@ -70,16 +106,18 @@ func newGUI() *guiAPI {
 
   var window *sdl.Window
   var renderer *sdl.Renderer
-  var aMask uint32 = 0
-  var rMask uint32 = 0
-  var gMask uint32 = 0
-  var bMask uint32 = 0
+  var screen *sdlRenderer
 
   cli := (*menu.Menu)(nil)
   hist := history.New()
 
+  // the last clip copied, pasted with its flags while the system
+  // clipboard still holds its text
+  clip := (*prose.Clip)(nil)
+
   view := box.Box{0, 0, 800, 600}
   mouse := box.Box{0, 0, 1, 1}
+  layout := newLayout()
 
   self.box = func() box.Box {
     return view
@@ -97,28 +135,57 @@ func newGUI() *guiAPI {
     view = box.Box{view.X, view.Y, w, h}
   }
 
+  // redraw only when something on screen may have changed
+  dirty := true
+
+  // text an input method is composing, shown in the focused word
+  composing := ""
+
+  var notice *image.RGBA
+  var noticeTime time.Time
+
+  self.notify = func(msg string) {
+    note(msg)
+    notice = text.DrawCache(Alert, 2.0, msg)
+    noticeTime = time.Now()
+    dirty = true
+  }
+
+  check := func(err error) bool {
+    if err != nil {
+      self.notify(err.Error())
+      return false
+    }
+    return true
+  }
+
   command := func(cmd string) bool {
-    doc.Save()
+    saved := check(doc.Save())
     fields := strings.Fields(cmd)
 
+    // never discard a document that failed to save
     if len(fields) == 2 && fields[0] == "load" {
-      doc.Load(fields[1])
-      return true
+      return saved && check(doc.Load(fields[1]))
     }
 
     if len(fields) == 1 && fields[0] == "load" {
-      doc.Load("")
-      return true
+      return saved && check(doc.Load(""))
     }
 
     if len(fields) == 1 && fields[0] == "save" {
-      doc.Save()
-      return true
+      return saved
     }
 
     if len(fields) == 2 && fields[0] == "save" {
-      doc.ReSave(fields[1])
-      return true
+      return check(doc.ReSave(fields[1]))
+    }
+
+    if len(fields) == 2 && fields[0] == "import" {
+      return check(doc.Import(fields[1]))
+    }
+
+    if len(fields) == 3 && fields[0] == "export" {
+      return check(doc.Export(fields[1], fields[2]))
     }
 
     if len(fields) == 2 && fields[0] == "autocomplete" {
@@ -126,8 +193,13 @@ func newGUI() *guiAPI {
       return true
     }
 
-    if len(fields) == 3 && fields[0] == "set" {
-      doc.Set(fields[1], fields[2])
+    if len(fields) >= 3 && fields[0] == "set" {
+      doc.Set(fields[1], strings.Join(fields[2:], " "))
+      return true
+    }
+
+    if len(fields) >= 2 && fields[0] == "meta" {
+      doc.SetMeta(fields[1], strings.Join(fields[2:], " "))
       return true
     }
 
@@ -139,359 +211,202 @@ func newGUI() *guiAPI {
     return false
   }
 
-  if sdl.BYTEORDER == sdl.BIG_ENDIAN {
-    aMask = 0x000000ff
-    bMask = 0x0000ff00
-    gMask = 0x00ff0000
-    rMask = 0xff000000
-  } else {
-    rMask = 0x000000ff
-    gMask = 0x0000ff00
-    bMask = 0x00ff0000
-    aMask = 0xff000000
-  }
-
-  var getTexture func(*image.RGBA) *sdl.Texture
-  var dropTexture func(*image.RGBA)
-
-  {
-    cache := make(map[*image.RGBA]*sdl.Texture)
-
-    create := func(rgba *image.RGBA) *sdl.Texture {
-      rect := rgba.Bounds()
-      w := rect.Dx()
-      h := rect.Dy()
-
-      surface, err := sdl.CreateRGBSurfaceFrom(
-        unsafe.Pointer(&rgba.Pix[0]),
-        w,
-        h,
-        32,
-        w*4,
-        rMask,
-        gMask,
-        bMask,
-        aMask,
-      )
-      assert(err)
-
-      texture, err := renderer.CreateTextureFromSurface(surface)
-      assert(err)
-
-      surface.Free()
-      return texture
-    }
-
-    getTexture = func(img *image.RGBA) *sdl.Texture {
-      if _, ok := cache[img]; !ok {
-        cache[img] = create(img)
-      }
-      return cache[img]
-    }
-
-    dropTexture = func(img *image.RGBA) {
-      if _, ok := cache[img]; ok {
-        cache[img].Destroy()
-        delete(cache, img)
-      }
+  // The document and everything laid out from it belong to the goroutine
+  // running tick. Input handlers and compositing run on SDL's thread within
+  // sdl.Do while that goroutine waits, so nothing touches the document
+  // concurrently. A layout is never changed once built, so cursor movement
+  // always reads one consistent frame.
+  stale := false
+
+  // placed returns a layout of the document as it is now, laying it out
+  // again if keys earlier in this batch have changed it.
+  placed := func() *layoutAPI {
+    if stale {
+      stale = false
+      layout = drawDoc(doc, layout, view, composing)
     }
+    return layout
   }
 
-  var fps uint64
-  var fps_rgba *image.RGBA
-  var fps_from time.Time
-  var fps_tick uint64
-
-  self.tick = func() {
-    tick := tick
-    self.jobs()
-
-    sprites := make(chan *sprite, 1000)
-    go func() {
-      doc.draw(view, sprites)
-      close(sprites)
-    }()
-
-    sdl.Do(func() {
+  draw := func() {
+    stale = false
+    layout = drawDoc(doc, layout, view, composing)
 
-      renderer.SetDrawColor(0, 0, 0, 0)
-      renderer.Clear()
+    frame := append([]*sprite{}, layout.sprites...)
 
-      textures := make([]*sdl.Texture, 0)
-      srects := make([]*sdl.Rect, 0)
-      drects := make([]*sdl.Rect, 0)
-      angles := make([]float64, 0)
-      uncache := make([]*image.RGBA, 0)
+    if cli != nil {
 
-      layers := [Layers][]*sprite{}
-      for i := BackGround; i < Layers; i++ {
-        layers[i] = []*sprite{}
-      }
-      for s := range sprites {
-        layers[s.layer] = append(layers[s.layer], s)
-      }
+      input := cli.Input()
+      matches := cli.Matches()
+      position := cli.Position()
 
-      for i := BackGround; i < Layers; i++ {
-        for _, s := range layers[i] {
-          src := boxSDL(s.src)
-          dst := boxSDL(s.dst)
-
-          textures = append(textures, getTexture(s.rgba))
-          srects = append(srects, &src)
-          drects = append(drects, &dst)
-          angles = append(angles, s.angle)
-          if !s.cache {
-            uncache = append(uncache, s.rgba)
-          }
-        }
+      x := 0
+      if len(input) > 0 {
+        rgba := text.Draw(Light, 2.0, input)
+        rect := rgba.Bounds()
+        frame = append(frame, overlay(rgba, x, view.H-50+((50-rect.Dy())/2), false))
+        x += rect.Dx() + 100
       }
 
-      if cli != nil {
-
-        input := cli.Input()
-        matches := cli.Matches()
-        position := cli.Position()
-
-        x := 0
-        if len(input) > 0 {
-          rgba := text.Draw(Light, 2.0, input)
-          rect := rgba.Bounds()
+      for i := 0; i < len(matches) && x < view.W; i++ {
+        match := matches[i]
 
-          dst := boxSDL(box.Box{x, view.H - 50 + ((50 - rect.Dy()) / 2), rect.Dx(), rect.Dy()})
+        var rgba *image.RGBA
 
-          textures = append(textures, getTexture(rgba))
-          uncache = append(uncache, rgba)
-          srects = append(srects, nil)
-          drects = append(drects, &dst)
-          angles = append(angles, 0.0)
-
-          x += rect.Dx() + 100
+        if position == i {
+          rgba = text.DrawCache(Light, 2.0, match)
+        } else {
+          rgba = text.DrawCache(Dark, 2.0, match)
         }
 
-        for i := 0; i < len(matches) && x < view.W; i++ {
-          match := matches[i]
-
-          var rgba *image.RGBA
-
-          if position == i {
-            rgba = text.DrawCache(Light, 2.0, match)
-          } else {
-            rgba = text.DrawCache(Dark, 2.0, match)
-          }
-
-          rect := rgba.Bounds()
-
-          dst := boxSDL(box.Box{x, view.H - 50 + ((50 - rect.Dy()) / 2), rect.Dx(), rect.Dy()})
-
-          textures = append(textures, getTexture(rgba))
-          srects = append(srects, nil)
-          drects = append(drects, &dst)
-          angles = append(angles, 0.0)
-
-          x += rect.Dx() + 50
-        }
+        rect := rgba.Bounds()
+        frame = append(frame, overlay(rgba, x, view.H-50+((50-rect.Dy())/2), true))
+        x += rect.Dx() + 50
       }
+    }
 
-      if time.Since(fps_from) >= time.Second {
-        n := uint64(tick) - fps_tick
-        fps_from = time.Now()
-        fps_tick = uint64(tick)
-        if fps != n {
-          fps = n
-          fps_rgba = text.DrawCache(Dark, 2.0, fmt.Sprintf("%d", fps))
-        }
-      }
+    if notice != nil {
+      frame = append(frame, overlay(notice, 0, 0, true))
+    }
 
-      if fps_rgba != nil {
-        rgba := fps_rgba
-        rect := rgba.Bounds()
-        textures = append(textures, getTexture(rgba))
-        srects = append(srects, nil)
-        drects = append(drects, &sdl.Rect{int32(view.W) - int32(rect.Dx()), 0, int32(rect.Dx()), int32(rect.Dy())})
-        angles = append(angles, 0.0)
-      }
+    // input methods place their candidate windows by the focused word
+    caret := layout.Word(doc.Paragraph().Word())
+    rect := sdl.Rect{int32(caret.Min.X), int32(caret.Min.Y), int32(caret.Dx()), int32(caret.Dy())}
 
-      renderer.CopyBatch(textures, srects, drects, angles)
+    sdl.Do(func() {
+      composite(screen, frame)
+      sdl.SetTextInputRect(&rect)
+    })
+  }
 
-      for _, img := range uncache {
-        dropTexture(img)
-      }
+  // tick draws if needed, then sleeps until input arrives or the deadline
+  // passes, and handles whatever input there is.
+  self.tick = func(deadline time.Time) {
+    self.jobs()
 
-      renderer.Present()
+    if notice != nil && time.Since(noticeTime) > noticeTimeout {
+      notice = nil
+      dirty = true
+    }
 
-      pressed := false
+    if dirty {
+      dirty = false
+      draw()
+    }
 
-      mod := sdl.GetModState()
-      shift := mod&sdl.KMOD_SHIFT != 0
-      ctrl := mod&sdl.KMOD_CTRL != 0
+    if notice != nil && noticeTime.Add(noticeTimeout).Before(deadline) {
+      deadline = noticeTime.Add(noticeTimeout)
+    }
 
-      editKey := func(key sdl.Keycode) {
-        doc.Insert(sdl.GetKeyName(key))
-      }
+    sdl.Do(func() {
 
-      editKeyCase := func(key sdl.Keycode) {
-        chr := sdl.GetKeyName(key)
-        if !shift {
-          chr = strings.ToLower(chr)
-        }
-        doc.Insert(chr)
+      // set from each key event in turn, for the handlers below
+      shift := false
+      ctrl := false
+
+      // Typed characters arrive as text, in whatever layout or input
+      // method the writer uses. These ones toggle punctuation flags
+      // instead of joining the word.
+      docTyping := map[rune]func(){
+        ' ': doc.Space,
+        '.': doc.Period,
+        ',': doc.Comma,
+        '!': doc.Exclaim,
+        '?': doc.Question,
+        '"': doc.DQuote,
+        '(': doc.Paren,
+        ')': doc.Paren,
+        ':': doc.Colon,
+        ';': doc.SemiColon,
+        '-': doc.Hyphen,
       }
 
-      editShiftPair := func(key sdl.Keycode, alt sdl.Keycode) {
-        chr := sdl.GetKeyName(key)
-        if shift {
-          chr = sdl.GetKeyName(alt)
+      docText := func(str string) {
+        for _, r := range str {
+          if handle := docTyping[r]; handle != nil {
+            handle()
+            continue
+          }
+          doc.Insert(string(r))
         }
-        doc.Insert(chr)
       }
 
       docEditing := map[sdl.Keycode]func(){
-        sdl.K_a: func() {
-          editKeyCase(sdl.K_a)
-        },
-        sdl.K_b: func() {
-          editKeyCase(sdl.K_b)
-        },
         sdl.K_c: func() {
-          editKeyCase(sdl.K_c)
-        },
-        sdl.K_d: func() {
-          editKeyCase(sdl.K_d)
-        },
-        sdl.K_e: func() {
-          editKeyCase(sdl.K_e)
-        },
-        sdl.K_f: func() {
-          editKeyCase(sdl.K_f)
-        },
-        sdl.K_g: func() {
-          editKeyCase(sdl.K_g)
-        },
-        sdl.K_h: func() {
-          editKeyCase(sdl.K_h)
-        },
-        sdl.K_i: func() {
-          editKeyCase(sdl.K_i)
-        },
-        sdl.K_j: func() {
-          editKeyCase(sdl.K_j)
-        },
-        sdl.K_k: func() {
-          editKeyCase(sdl.K_k)
-        },
-        sdl.K_l: func() {
-          editKeyCase(sdl.K_l)
-        },
-        sdl.K_m: func() {
-          editKeyCase(sdl.K_m)
-        },
-        sdl.K_n: func() {
-          editKeyCase(sdl.K_n)
-        },
-        sdl.K_o: func() {
-          editKeyCase(sdl.K_o)
-        },
-        sdl.K_p: func() {
-          editKeyCase(sdl.K_p)
-        },
-        sdl.K_q: func() {
-          editKeyCase(sdl.K_q)
-        },
-        sdl.K_r: func() {
-          editKeyCase(sdl.K_r)
-        },
-        sdl.K_s: func() {
-          editKeyCase(sdl.K_s)
-        },
-        sdl.K_t: func() {
-          editKeyCase(sdl.K_t)
-        },
-        sdl.K_u: func() {
-          editKeyCase(sdl.K_u)
-        },
-        sdl.K_v: func() {
-          editKeyCase(sdl.K_v)
-        },
-        sdl.K_w: func() {
-          editKeyCase(sdl.K_w)
+          if ctrl {
+            if c := doc.Copy(); c != nil {
+              clip = c
+              sdl.SetClipboardText(clip.Text())
+            }
+          }
         },
+
         sdl.K_x: func() {
-          editKeyCase(sdl.K_x)
-        },
-        sdl.K_y: func() {
-          editKeyCase(sdl.K_y)
+          if ctrl {
+            if c := doc.Cut(); c != nil {
+              clip = c
+              sdl.SetClipboardText(clip.Text())
+            }
+          }
         },
 
-        sdl.K_1: func() {
-          if shift {
-            doc.Exclaim()
-            return
+        sdl.K_v: func() {
+          if ctrl {
+            str := sdl.GetClipboardText()
+            if clip != nil && str == clip.Text() {
+              doc.Paste(clip)
+              return
+            }
+            doc.PasteText(str)
           }
-          editKey(sdl.K_1)
         },
 
-        sdl.K_2: func() {
-          editShiftPair(sdl.K_2, sdl.K_AT)
+        sdl.K_z: func() {
+          if ctrl && shift {
+            doc.Redo()
+            return
+          }
+          if ctrl {
+            doc.Undo()
+          }
         },
 
         sdl.K_3: func() {
           if ctrl {
             doc.Heading()
-            return
           }
-          editShiftPair(sdl.K_3, sdl.K_HASH)
         },
 
         sdl.K_4: func() {
           if ctrl {
             doc.Variable()
-            return
           }
-          editShiftPair(sdl.K_4, sdl.K_DOLLAR)
-        },
-
-        sdl.K_5: func() {
-          editShiftPair(sdl.K_5, sdl.K_PERCENT)
         },
 
         sdl.K_6: func() {
           if ctrl {
             doc.UCFirst()
-            return
           }
-          editShiftPair(sdl.K_6, sdl.K_CARET)
-        },
-
-        sdl.K_7: func() {
-          editShiftPair(sdl.K_7, sdl.K_AMPERSAND)
         },
 
         sdl.K_8: func() {
           if ctrl {
             doc.Bullet()
-            return
           }
-          editShiftPair(sdl.K_8, sdl.K_ASTERISK)
         },
 
-        sdl.K_9: func() {
-          if shift {
-            doc.Paren()
-            return
+        sdl.K_MINUS: func() {
+          if ctrl {
+            doc.Emphasis()
           }
-          editKey(sdl.K_9)
         },
 
-        sdl.K_0: func() {
-          if shift {
-            doc.Paren()
+        sdl.K_ESCAPE: func() {
+          if doc.Selection() != nil {
+            doc.Deselect()
             return
           }
-          editKey(sdl.K_0)
-        },
-
-        sdl.K_ESCAPE: func() {
-          cli = menu.New("", []string{"load", "save", "set", "drop", "autocomplete"})
+          cli = menu.New("", []string{"load", "save", "import", "export", "set", "drop", "meta", "autocomplete"})
           hist.Last()
         },
 
@@ -504,7 +419,7 @@ func newGUI() *guiAPI {
             doc.ShiftUp()
             return
           }
-          doc.Up()
+          doc.Up(placed())
         },
 
         sdl.K_DOWN: func() {
@@ -512,14 +427,30 @@ func newGUI() *guiAPI {
             doc.ShiftDown()
             return
           }
-          doc.Down()
+          doc.Down(placed())
         },
 
         sdl.K_LEFT: func() {
+          if shift {
+            doc.SelectLeft()
+            return
+          }
+          if ctrl {
+            doc.CaretLeft()
+            return
+          }
           doc.Left()
         },
 
         sdl.K_RIGHT: func() {
+          if shift {
+            doc.SelectRight()
+            return
+          }
+          if ctrl {
+            doc.CaretRight()
+            return
+          }
           doc.Right()
         },
 
@@ -528,6 +459,10 @@ func newGUI() *guiAPI {
         },
 
         sdl.K_BACKSPACE: func() {
+          if ctrl {
+            doc.DeleteWord()
+            return
+          }
           doc.BackSpace()
         },
 
@@ -535,36 +470,12 @@ func newGUI() *guiAPI {
           doc.Delete()
         },
 
-        sdl.K_PERIOD: func() {
-          doc.Period()
-        },
-
-        sdl.K_COMMA: func() {
-          doc.Comma()
-        },
-
-        sdl.K_QUOTE: func() {
-          if shift {
-            doc.DQuote()
-            return
-          }
-          editKey(sdl.K_QUOTE)
-        },
-
-        sdl.K_SLASH: func() {
-          if shift {
-            doc.Question()
-            return
-          }
-          editKey(sdl.K_SLASH)
-        },
-
         sdl.K_HOME: func() {
           if ctrl {
             doc.Top()
             return
           }
-          doc.Home()
+          doc.Home(placed())
         },
 
         sdl.K_END: func() {
@@ -572,162 +483,16 @@ func newGUI() *guiAPI {
             doc.Bottom()
             return
           }
-          doc.End()
-        },
-
-        sdl.K_SEMICOLON: func() {
-          if shift {
-            doc.Colon()
-            return
-          }
-          doc.SemiColon()
-        },
-
-        sdl.K_MINUS: func() {
-          if ctrl {
-            doc.Emphasis()
-            return
-          }
-          doc.Hyphen()
-        },
-
-        sdl.K_SPACE: func() {
-          doc.Space()
+          doc.End(placed())
         },
       }
 
-      cliKeyCase := func(key sdl.Keycode) {
-        chr := sdl.GetKeyName(key)
-        if !shift {
-          chr = strings.ToLower(chr)
-        }
-        cli.Ins(chr)
-      }
-
-      cliShiftPair := func(key sdl.Keycode, alt sdl.Keycode) {
-        chr := sdl.GetKeyName(key)
-        if shift {
-          chr = sdl.GetKeyName(alt)
-        }
-        cli.Ins(chr)
+      // typed accents must match the composed words autocomplete offers
+      cliText := func(str string) {
+        cli.Ins(prose.Compose(str))
       }
 
       cliEditing := map[sdl.Keycode]func(){
-        sdl.K_a: func() {
-          cliKeyCase(sdl.K_a)
-        },
-        sdl.K_b: func() {
-          cliKeyCase(sdl.K_b)
-        },
-        sdl.K_c: func() {
-          cliKeyCase(sdl.K_c)
-        },
-        sdl.K_d: func() {
-          cliKeyCase(sdl.K_d)
-        },
-        sdl.K_e: func() {
-          cliKeyCase(sdl.K_e)
-        },
-        sdl.K_f: func() {
-          cliKeyCase(sdl.K_f)
-        },
-        sdl.K_g: func() {
-          cliKeyCase(sdl.K_g)
-        },
-        sdl.K_h: func() {
-          cliKeyCase(sdl.K_h)
-        },
-        sdl.K_i: func() {
-          cliKeyCase(sdl.K_i)
-        },
-        sdl.K_j: func() {
-          cliKeyCase(sdl.K_j)
-        },
-        sdl.K_k: func() {
-          cliKeyCase(sdl.K_k)
-        },
-        sdl.K_l: func() {
-          cliKeyCase(sdl.K_l)
-        },
-        sdl.K_m: func() {
-          cliKeyCase(sdl.K_m)
-        },
-        sdl.K_n: func() {
-          cliKeyCase(sdl.K_n)
-        },
-        sdl.K_o: func() {
-          cliKeyCase(sdl.K_o)
-        },
-        sdl.K_p: func() {
-          cliKeyCase(sdl.K_p)
-        },
-        sdl.K_q: func() {
-          cliKeyCase(sdl.K_q)
-        },
-        sdl.K_r: func() {
-          cliKeyCase(sdl.K_r)
-        },
-        sdl.K_s: func() {
-          cliKeyCase(sdl.K_s)
-        },
-        sdl.K_t: func() {
-          cliKeyCase(sdl.K_t)
-        },
-        sdl.K_u: func() {
-          cliKeyCase(sdl.K_u)
-        },
-        sdl.K_v: func() {
-          cliKeyCase(sdl.K_v)
-        },
-        sdl.K_w: func() {
-          cliKeyCase(sdl.K_w)
-        },
-        sdl.K_x: func() {
-          cliKeyCase(sdl.K_x)
-        },
-        sdl.K_y: func() {
-          cliKeyCase(sdl.K_y)
-        },
-        sdl.K_1: func() {
-          cliShiftPair(sdl.K_1, sdl.K_EXCLAIM)
-        },
-        sdl.K_2: func() {
-          cliShiftPair(sdl.K_2, sdl.K_AT)
-        },
-        sdl.K_3: func() {
-          cliShiftPair(sdl.K_3, sdl.K_HASH)
-        },
-        sdl.K_4: func() {
-          cliShiftPair(sdl.K_4, sdl.K_DOLLAR)
-        },
-        sdl.K_5: func() {
-          cliShiftPair(sdl.K_5, sdl.K_PERCENT)
-        },
-        sdl.K_6: func() {
-          cliShiftPair(sdl.K_6, sdl.K_CARET)
-        },
-        sdl.K_7: func() {
-          cliShiftPair(sdl.K_7, sdl.K_AMPERSAND)
-        },
-        sdl.K_8: func() {
-          cliShiftPair(sdl.K_8, sdl.K_ASTERISK)
-        },
-        sdl.K_9: func() {
-          cliShiftPair(sdl.K_9, sdl.K_LEFTPAREN)
-        },
-        sdl.K_0: func() {
-          cliShiftPair(sdl.K_0, sdl.K_RIGHTPAREN)
-        },
-        sdl.K_SPACE: func() {
-          cli.Ins(" ")
-        },
-        sdl.K_PERIOD: func() {
-          cliShiftPair(sdl.K_PERIOD, sdl.K_GREATER)
-        },
-        sdl.K_COMMA: func() {
-          cliShiftPair(sdl.K_COMMA, sdl.K_LESS)
-        },
-
         sdl.K_ESCAPE: func() {
           cli = nil
         },
@@ -763,22 +528,30 @@ func newGUI() *guiAPI {
         },
       }
 
-      for {
+      // SDL queues input while a frame is drawn, so every key is handled
+      // in order however slowly frames arrive.
+      wait := int(time.Until(deadline) / time.Millisecond)
+      if wait < 1 {
+        wait = 1
+      }
 
-        ev := sdl.PollEvent()
-        if ev == nil {
-          break
-        }
+      for ev := sdl.WaitEventTimeout(wait); ev != nil; ev = sdl.PollEvent() {
 
         switch ev.(type) {
 
         case *sdl.QuitEvent:
-          doc.Save()
+          // a failed save cancels the first quit so the writer sees why
+          if !check(doc.Save()) && !forceQuit {
+            forceQuit = true
+            continue
+          }
           quit = true
           return
 
         case *sdl.WindowEvent:
           wev := ev.(*sdl.WindowEvent)
+          dirty = true
+          stale = true
           switch wev.Event {
 
           case sdl.WINDOWEVENT_SIZE_CHANGED:
@@ -790,29 +563,51 @@ func newGUI() *guiAPI {
           mouse.X = int(mev.X) + view.X - view.W/2
           mouse.Y = int(mev.Y) + view.Y - view.H/2
 
-        case *sdl.KeyDownEvent:
+        case *sdl.TextEditingEvent:
+          composing = cstr(ev.(*sdl.TextEditingEvent).Text[:])
+          dirty = true
 
-          if pressed {
-            continue
-          }
+        case *sdl.TextInputEvent:
+          composing = ""
+          dirty = true
+          stale = true
 
-          pressed = true
+          // SDL sends no text for Ctrl shortcuts, so whatever arrives
+          // here was typed, however the modifiers stand by now
+          str := cstr(ev.(*sdl.TextInputEvent).Text[:])
+          if cli != nil {
+            cliText(str)
+          } else {
+            docText(str)
+          }
 
-          if cli == nil {
+        case *sdl.KeyDownEvent:
+          kev := ev.(*sdl.KeyDownEvent)
+          key := kev.Keysym.Sym
 
-            handle := docEditing[ev.(*sdl.KeyDownEvent).Keysym.Sym]
+          if kev.Repeat != 0 && !repeatable[key] {
+            continue
+          }
 
-            if handle != nil {
-              handle()
-            }
+          // keys belong to the input method while it composes
+          if len(composing) > 0 {
+            continue
+          }
 
-          } else {
+          // modifiers as they were for this key, not for the last in the queue
+          mod := sdl.Keymod(kev.Keysym.Mod)
+          shift = mod&sdl.KMOD_SHIFT != 0
+          ctrl = mod&sdl.KMOD_CTRL != 0
 
-            handle := cliEditing[ev.(*sdl.KeyDownEvent).Keysym.Sym]
+          handlers := docEditing
+          if cli != nil {
+            handlers = cliEditing
+          }
 
-            if handle != nil {
-              handle()
-            }
+          if handle := handlers[key]; handle != nil {
+            handle()
+            dirty = true
+            stale = true
           }
         }
       }
@@ -847,6 +642,9 @@ func newGUI() *guiAPI {
     err = renderer.
       SetDrawBlendMode(sdl.BLENDMODE_BLEND)
     assert(err)
+
+    screen = newSDLRenderer(renderer)
+    sdl.StartTextInput()
   })
 
   return self
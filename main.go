@@ -3,46 +3,66 @@ package main
 import (
   "flag"
   "github.com/seanpringle/go-sdl2/sdl"
+  "github.com/seanpringle/prose/prose"
   "log"
-  "math"
   "os"
   "runtime/pprof"
-  "sync/atomic"
   "time"
 )
 
-type tock uint64
+// autosave is how often the editor saves while the writer works.
+const autosave = time.Minute
 
 var (
-  profile  *bool = flag.Bool("profile", false, "cpu profile")
-  gui      *guiAPI
-  doc      *docAPI
-  sequence uint64 = 0
-  tick     tock   = 0
-  fps      uint   = 30
+  opts *options = newOptions(flag.CommandLine)
+  gui  *guiAPI
+  doc  *prose.DocAPI
 )
 
-func note(arg ...interface{}) {
-  log.Println(arg...)
+// options are the editor's flags. Anything left after them names the
+// document, or a headless command.
+type options struct {
+  profile  *bool
+  backups  *int
+  fontPath *string
 }
 
-func id() uint64 {
-  return atomic.AddUint64(&sequence, 1)
+func newOptions(flags *flag.FlagSet) *options {
+  def := prose.DefaultOptions()
+  self := &options{}
+  self.profile = flags.Bool("profile", false, "cpu profile")
+  self.backups = flags.Int("backups", def.Backups, "timestamped backups to keep beside a document")
+  self.fontPath = flags.String("font", def.Font, "TrueType font embedded in PDF export")
+  return self
 }
 
-func seconds(sec float64) tock {
-  return tock(math.Max(1, sec*float64(fps)))
+// doc returns the document settings the flags ask for.
+func (self *options) doc() prose.Options {
+  return prose.Options{
+    Backups: *self.backups,
+    Font:    *self.fontPath,
+  }
 }
 
-func ticks(n tock) float64 {
-  return math.Max(1.0, float64(n)) / float64(fps)
+// documentPath is the document named after the flags, or "" for the
+// default.
+func documentPath(flags *flag.FlagSet) string {
+  return flags.Arg(0)
+}
+
+func note(arg ...interface{}) {
+  log.Println(arg...)
 }
 
 func main() {
 
   flag.Parse()
 
-  if *profile {
+  if cmd := flag.Arg(0); commands[cmd].run != nil {
+    os.Exit(headless(cmd, flag.Args()[1:]))
+  }
+
+  if *opts.profile {
     file, err := os.Create("profile")
     if err != nil {
       panic(err)
@@ -55,24 +75,30 @@ func main() {
 
     gui = newGUI()
 
-    path := ""
-    for _, arg := range os.Args[1:] {
-      path = arg
+    // flag values such as -font's are not documents
+    var err error
+    doc, err = prose.NewDoc(documentPath(flag.CommandLine), opts.doc())
+    if err != nil {
+      gui.notify(err.Error())
     }
 
-    doc = newDoc(path)
-
-    ticker := time.NewTicker(time.Millisecond * time.Duration(1000/fps))
+    saved := time.Now()
 
+    // the gui sleeps until there is input or an autosave is due
     for !gui.done() {
-      <-ticker.C
-      tick++
-      doc.tick()
-      gui.tick()
+      gui.tick(saved.Add(autosave))
+      if err := doc.JournalErr(); err != nil {
+        gui.notify(err.Error())
+      }
+      if time.Since(saved) >= autosave {
+        saved = time.Now()
+        if err := doc.Save(); err != nil {
+          gui.notify(err.Error())
+        }
+      }
     }
 
-    ticker.Stop()
-    doc.exit()
+    doc.Close()
     gui.exit()
   })
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+  "flag"
+  "io/ioutil"
+  "testing"
+)
+
+func TestDocumentPath(t *testing.T) {
+  cases := []struct {
+    args    []string
+    path    string
+    backups int
+    font    string
+  }{
+    {[]string{}, "", 5, ""},
+    {[]string{"novel.prose"}, "novel.prose", 5, ""},
+    {[]string{"-backups", "3"}, "", 3, ""},
+    {[]string{"-backups", "3", "novel.prose"}, "novel.prose", 3, ""},
+    {[]string{"-font", "My.ttf"}, "", 5, "My.ttf"},
+    {[]string{"-font", "My.ttf", "novel.prose"}, "novel.prose", 5, "My.ttf"},
+    {[]string{"-backups=0", "-font=My.ttf", "novel.prose"}, "novel.prose", 0, "My.ttf"},
+  }
+
+  for _, c := range cases {
+    flags := flag.NewFlagSet("prose", flag.ContinueOnError)
+    flags.SetOutput(ioutil.Discard)
+    opts := newOptions(flags)
+    if err := flags.Parse(c.args); err != nil {
+      t.Fatalf("%v: %v", c.args, err)
+    }
+    if path := documentPath(flags); path != c.path {
+      t.Errorf("%v: path %q, want %q", c.args, path, c.path)
+    }
+    if *opts.backups != c.backups {
+      t.Errorf("%v: backups %d, want %d", c.args, *opts.backups, c.backups)
+    }
+    if c.font != "" && *opts.fontPath != c.font {
+      t.Errorf("%v: font %q, want %q", c.args, *opts.fontPath, c.font)
+    }
+    if d := opts.doc(); d.Backups != *opts.backups || d.Font != *opts.fontPath {
+      t.Errorf("%v: document options %+v do not match the flags", c.args, d)
+    }
+  }
+}
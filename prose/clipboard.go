@@ -0,0 +1,121 @@
+package prose
+
+import (
+  "strings"
+)
+
+// Clip is a copied run of words, grouped by paragraph, keeping their
+// flags and paragraph styles for pasting back into a document.
+type Clip struct {
+  paras []*ParaAPI
+}
+
+// clone copies a paragraph's words, or only those in a set, detached
+// from any list but still reading variables through doc.
+func clone(doc *DocAPI, para *ParaAPI, only map[*WordAPI]bool) *ParaAPI {
+  dup := newPara(doc)
+  dup.style = para.style
+  for _, word := range para.Words() {
+    if only == nil || only[word] {
+      w := newWord(dup)
+      w.text = word.text
+      w.flags = word.flags
+      dup.AddWord(w)
+    }
+  }
+  return dup
+}
+
+// Text renders a clip as plain text for other programs, one paragraph
+// per block.
+func (self *Clip) Text() string {
+  blocks := []string{}
+  for _, para := range self.paras {
+    blocks = append(blocks, para.Plain())
+  }
+  return strings.Join(blocks, "\n\n")
+}
+
+// Copy returns the selection as a clip, or nil if nothing is selected.
+func (self *DocAPI) Copy() *Clip {
+  words := self.Selection()
+  if words == nil {
+    return nil
+  }
+
+  only := map[*WordAPI]bool{}
+  for _, word := range words {
+    only[word] = true
+  }
+
+  clip := &Clip{}
+  for _, para := range self.spanned() {
+    clip.paras = append(clip.paras, clone(self, para, only))
+  }
+  return clip
+}
+
+// Cut copies the selection and deletes it as one undoable edit.
+func (self *DocAPI) Cut() *Clip {
+  clip := self.Copy()
+  if clip != nil {
+    self.edit(nil, func() {
+      self.deleteSelection()
+    })
+  }
+  return clip
+}
+
+// Paste inserts a clip after the focused word. A clip from several
+// paragraphs splits the focused one there: its first paragraph's words
+// join the head, its last takes the rest of the words, and those between
+// go in whole, so pasting back what was cut restores the text.
+func (self *DocAPI) Paste(clip *Clip) {
+  if clip == nil {
+    return
+  }
+  paras := []*ParaAPI{}
+  for _, para := range clip.paras {
+    paras = append(paras, clone(self, para, nil))
+  }
+  self.paste(paras)
+}
+
+// PasteText pastes plain text from another program, reading punctuation,
+// quotes and paragraphs from it as a text import would.
+func (self *DocAPI) PasteText(str string) {
+  in, _ := importText(self, []byte(str))
+  self.paste(in.paras)
+}
+
+func (self *DocAPI) paste(paras []*ParaAPI) {
+  self.Deselect()
+  if len(paras) == 0 {
+    return
+  }
+
+  self.edit(nil, func() {
+    defer self.check()
+    head := self.Paragraph()
+
+    // the words after the cursor move to the end of the last paragraph
+    rest := map[*WordAPI]bool{}
+    tail := newPara(self)
+    if len(paras) > 1 {
+      for e := head.node.Next(); e != nil; e = e.Next() {
+        rest[e.Value.(*WordAPI)] = true
+        tail.AddWord(e.Value.(*WordAPI))
+      }
+    }
+    head.remove(rest)
+
+    for _, word := range paras[0].Words() {
+      head.AddWord(word)
+    }
+    for _, para := range paras[1:] {
+      self.node = self.list.InsertAfter(para, self.node)
+      self.reordered()
+    }
+    self.Paragraph().Join(tail)
+  })
+}
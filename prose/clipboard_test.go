@@ -0,0 +1,132 @@
+package prose
+
+import (
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+func text(doc *DocAPI) string {
+  return strings.Join(plain(doc.Paragraphs()), " | ")
+}
+
+// selectWords selects n words from the given word of a paragraph on.
+func selectWords(doc *DocAPI, para int, word int, n int) {
+  doc.Seek(doc.Paragraphs()[para])
+  for i := 0; i < word; i++ {
+    doc.Right()
+  }
+  for i := 1; i < n; i++ {
+    doc.SelectRight()
+  }
+  if n == 1 {
+    doc.mark()
+  }
+}
+
+func TestCutPaste(t *testing.T) {
+  tests := []struct {
+    lines []string
+    para  int
+    word  int
+    n     int
+    cut   string
+    clip  string
+  }{
+    {[]string{"one two", "three four", "five six"}, 0, 1, 4, "one six", "two\n\nthree four\n\nfive"},
+    {[]string{"one two three"}, 0, 1, 1, "one three", "two"},
+    {[]string{"one two three"}, 0, 0, 2, "three", "one two"},
+    {[]string{"one two", "three four"}, 0, 0, 3, "four", "one two\n\nthree"},
+    {[]string{"one two", "three four"}, 0, 1, 3, "one", "two\n\nthree four"},
+    {[]string{"one", "two", "three"}, 0, 0, 3, "", "one\n\ntwo\n\nthree"},
+  }
+
+  for _, test := range tests {
+    doc := Create(filepath.Join(t.TempDir(), "clip.prose"), DefaultOptions())
+    typeParas(doc, test.lines...)
+    want := text(doc)
+
+    selectWords(doc, test.para, test.word, test.n)
+    clip := doc.Cut()
+    if clip == nil {
+      t.Fatalf("%s: nothing cut", want)
+    }
+    if got := clip.Text(); got != test.clip {
+      t.Errorf("%s: cut %q, want %q", want, got, test.clip)
+    }
+    if got := text(doc); got != test.cut {
+      t.Errorf("%s: left %q, want %q", want, got, test.cut)
+    }
+
+    doc.Paste(clip)
+    if got := text(doc); got != want {
+      t.Errorf("%s: pasting back gave %q", want, got)
+    }
+
+    // paste and cut each undo in one step
+    doc.Undo()
+    if got := text(doc); got != test.cut {
+      t.Errorf("%s: undoing the paste gave %q, want %q", want, got, test.cut)
+    }
+    doc.Undo()
+    if got := text(doc); got != want {
+      t.Errorf("%s: undoing the cut gave %q", want, got)
+    }
+  }
+}
+
+func TestCopy(t *testing.T) {
+  doc := Create(filepath.Join(t.TempDir(), "copy.prose"), DefaultOptions())
+  if doc.Copy() != nil {
+    t.Error("copied with nothing selected")
+  }
+
+  typeParas(doc, "a title", "one two", "three")
+  doc.Seek(doc.Paragraphs()[1])
+  doc.Right()
+  doc.Period()
+  doc.Seek(doc.Paragraphs()[0])
+  doc.Heading()
+  want := lines(doc)
+
+  selectWords(doc, 0, 1, 3)
+  clip := doc.Copy()
+  if got := lines(doc); got != want {
+    t.Errorf("copy changed the document\n%s", got)
+  }
+  if got := clip.Text(); got != "title\n\none two." {
+    t.Errorf("copied %q", got)
+  }
+  if clip.paras[0].Style() != Heading || !clip.paras[1].Words()[1].Is(Period) {
+    t.Error("copy lost a style or a flag")
+  }
+
+  // the clip is a copy, not the document's own words
+  doc.Paste(clip)
+  doc.Paste(clip)
+  if got := text(doc); got != "a title | one two. title | one two. title | one two. | three" {
+    t.Errorf("pasted twice %q", got)
+  }
+}
+
+func TestPasteText(t *testing.T) {
+  doc := Create(filepath.Join(t.TempDir(), "paste.prose"), DefaultOptions())
+  typeParas(doc, "one two", "three")
+  before := text(doc)
+  doc.Seek(doc.Paragraphs()[0])
+
+  doc.PasteText("alpha \"beta\".\n\ngamma")
+  if got := text(doc); got != `one alpha "beta." | gamma two | three` {
+    t.Errorf("pasted %q", got)
+  }
+
+  doc.Undo()
+  if got := text(doc); got != before {
+    t.Errorf("one undo gave %q, want %q", got, before)
+  }
+
+  doc.PasteText("   ")
+  if got := text(doc); got != before {
+    t.Errorf("pasting blank text gave %q", got)
+  }
+}
@@ -0,0 +1,104 @@
+package prose
+
+import (
+  "strings"
+)
+
+// Words typed or imported may spell an accented letter as one code point
+// or as a letter followed by a combining mark. Go has no normalisation
+// tables outside x/text, so Compose covers the common case by hand: a
+// Latin letter with one mark, as used across Western and Central Europe.
+
+// latinMarks lists, for each combining mark, pairs of a base letter and
+// the precomposed letter it makes.
+var latinMarks = map[rune][]rune{
+  0x0300: { // grave
+    'A', 0x00c0, 'E', 0x00c8, 'I', 0x00cc, 'O', 0x00d2, 'U', 0x00d9, 'a', 0x00e0,
+    'e', 0x00e8, 'i', 0x00ec, 'o', 0x00f2, 'u', 0x00f9,
+  },
+  0x0301: { // acute
+    'A', 0x00c1, 'C', 0x0106, 'E', 0x00c9, 'I', 0x00cd, 'L', 0x0139, 'N', 0x0143,
+    'O', 0x00d3, 'R', 0x0154, 'S', 0x015a, 'U', 0x00da, 'Y', 0x00dd, 'Z', 0x0179,
+    'a', 0x00e1, 'c', 0x0107, 'e', 0x00e9, 'i', 0x00ed, 'l', 0x013a, 'n', 0x0144,
+    'o', 0x00f3, 'r', 0x0155, 's', 0x015b, 'u', 0x00fa, 'y', 0x00fd, 'z', 0x017a,
+  },
+  0x0302: { // circumflex
+    'A', 0x00c2, 'C', 0x0108, 'E', 0x00ca, 'G', 0x011c, 'H', 0x0124, 'I', 0x00ce,
+    'J', 0x0134, 'O', 0x00d4, 'S', 0x015c, 'U', 0x00db, 'W', 0x0174, 'Y', 0x0176,
+    'a', 0x00e2, 'c', 0x0109, 'e', 0x00ea, 'g', 0x011d, 'h', 0x0125, 'i', 0x00ee,
+    'j', 0x0135, 'o', 0x00f4, 's', 0x015d, 'u', 0x00fb, 'w', 0x0175, 'y', 0x0177,
+  },
+  0x0303: { // tilde
+    'A', 0x00c3, 'I', 0x0128, 'N', 0x00d1, 'O', 0x00d5, 'U', 0x0168, 'a', 0x00e3,
+    'i', 0x0129, 'n', 0x00f1, 'o', 0x00f5, 'u', 0x0169,
+  },
+  0x0304: { // macron
+    'A', 0x0100, 'E', 0x0112, 'I', 0x012a, 'O', 0x014c, 'U', 0x016a, 'a', 0x0101,
+    'e', 0x0113, 'i', 0x012b, 'o', 0x014d, 'u', 0x016b,
+  },
+  0x0306: { // breve
+    'A', 0x0102, 'E', 0x0114, 'G', 0x011e, 'I', 0x012c, 'O', 0x014e, 'U', 0x016c,
+    'a', 0x0103, 'e', 0x0115, 'g', 0x011f, 'i', 0x012d, 'o', 0x014f, 'u', 0x016d,
+  },
+  0x0307: { // dot above
+    'C', 0x010a, 'E', 0x0116, 'G', 0x0120, 'I', 0x0130, 'Z', 0x017b, 'c', 0x010b,
+    'e', 0x0117, 'g', 0x0121, 'z', 0x017c,
+  },
+  0x0308: { // diaeresis
+    'A', 0x00c4, 'E', 0x00cb, 'I', 0x00cf, 'O', 0x00d6, 'U', 0x00dc, 'Y', 0x0178,
+    'a', 0x00e4, 'e', 0x00eb, 'i', 0x00ef, 'o', 0x00f6, 'u', 0x00fc, 'y', 0x00ff,
+  },
+  0x030a: { // ring
+    'A', 0x00c5, 'U', 0x016e, 'a', 0x00e5, 'u', 0x016f,
+  },
+  0x030b: { // double acute
+    'O', 0x0150, 'U', 0x0170, 'o', 0x0151, 'u', 0x0171,
+  },
+  0x030c: { // caron
+    'C', 0x010c, 'D', 0x010e, 'E', 0x011a, 'L', 0x013d, 'N', 0x0147, 'R', 0x0158,
+    'S', 0x0160, 'T', 0x0164, 'Z', 0x017d, 'c', 0x010d, 'd', 0x010f, 'e', 0x011b,
+    'l', 0x013e, 'n', 0x0148, 'r', 0x0159, 's', 0x0161, 't', 0x0165, 'z', 0x017e,
+  },
+  0x0327: { // cedilla
+    'C', 0x00c7, 'G', 0x0122, 'K', 0x0136, 'L', 0x013b, 'N', 0x0145, 'R', 0x0156,
+    'S', 0x015e, 'T', 0x0162, 'c', 0x00e7, 'g', 0x0123, 'k', 0x0137, 'l', 0x013c,
+    'n', 0x0146, 'r', 0x0157, 's', 0x015f, 't', 0x0163,
+  },
+  0x0328: { // ogonek
+    'A', 0x0104, 'E', 0x0118, 'I', 0x012e, 'U', 0x0172, 'a', 0x0105, 'e', 0x0119,
+    'i', 0x012f, 'u', 0x0173,
+  },
+}
+
+var composed map[[2]rune]rune
+
+func init() {
+  composed = map[[2]rune]rune{}
+  for mark, pairs := range latinMarks {
+    for i := 0; i+1 < len(pairs); i += 2 {
+      composed[[2]rune{pairs[i], mark}] = pairs[i+1]
+    }
+  }
+}
+
+// Compose replaces a Latin letter and a following combining mark with
+// the single letter they stand for, so words compare equal however they
+// were keyed. Other text passes through.
+func Compose(s string) string {
+  var out strings.Builder
+  prev := rune(-1)
+  for _, r := range s {
+    if c, ok := composed[[2]rune{prev, r}]; ok {
+      prev = c
+      continue
+    }
+    if prev >= 0 {
+      out.WriteRune(prev)
+    }
+    prev = r
+  }
+  if prev >= 0 {
+    out.WriteRune(prev)
+  }
+  return out.String()
+}
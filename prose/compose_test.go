@@ -0,0 +1,41 @@
+package prose
+
+import (
+  "testing"
+)
+
+func TestCompose(t *testing.T) {
+  tests := []struct {
+    text string
+    want string
+  }{
+    {"cafe\u0301", "caf\u00e9"},
+    {"caf\u00e9", "caf\u00e9"},
+    {"Ele\u0300ve", "El\u00e8ve"},
+    {"u\u0308ber", "\u00fcber"},
+    {"z\u030c", "\u017e"},
+    {"\u0131\u0308", "\u0131\u0308"},
+    {"\u0301acute", "\u0301acute"},
+    {"e\u0301\u0301", "\u00e9\u0301"},
+    {"x\u0301", "x\u0301"},
+    {"", ""},
+  }
+  for _, test := range tests {
+    if got := Compose(test.text); got != test.want {
+      t.Errorf("Compose(%q) = %q, want %q", test.text, got, test.want)
+    }
+  }
+}
+
+func TestWordListComposed(t *testing.T) {
+  para := newPara(nil)
+  for _, text := range []string{"cafe\u0301s", "caf\u00e9s"} {
+    w := newWord(para)
+    w.text = text
+    para.AddWord(w)
+  }
+  list := para.WordList(1)
+  if len(list) != 1 || list[0] != "caf\u00e9s" {
+    t.Errorf("WordList = %q, want one composed word", list)
+  }
+}
@@ -0,0 +1,799 @@
+package prose
+
+import (
+  "bufio"
+  "container/list"
+  "fmt"
+  "github.com/seanpringle/gostuff/workerpool"
+  "io/ioutil"
+  "os"
+  "sort"
+  "strings"
+)
+
+// DocAPI is a document: metadata, variables and a list of paragraphs
+// with a cursor. Editing methods act at the cursor and are undoable.
+type DocAPI struct {
+  list       *list.List
+  node       *list.Element
+  path       string
+  words      []string
+  vars       map[string]string
+  meta       map[string]string
+  undo       *undoAPI
+  journal    *journalAPI
+  journaling bool // only documents opened for editing keep a journal
+  anchor     *WordAPI
+  edited     bool // changed since last saved
+  opts       Options
+  order      []*ParaAPI // paragraphs in order, nil once the list changes
+}
+
+// NewDoc opens a document for editing, as Load. The document is usable
+// even when an error is returned.
+func NewDoc(path string, opts Options) (*DocAPI, error) {
+  self := &DocAPI{opts: opts}
+  self.vars = map[string]string{}
+  self.meta = map[string]string{}
+  err := self.Load(path)
+  return self, err
+}
+
+// Open reads a document for batch processing: no journal, and any
+// problem with the file is returned rather than papered over.
+func Open(path string, opts Options) (*DocAPI, error) {
+  self := &DocAPI{opts: opts}
+  if _, _, err := self.read(path); err != nil {
+    return nil, err
+  }
+  return self, nil
+}
+
+// Create starts an empty document that will Save to path, with no
+// journal.
+func Create(path string, opts Options) *DocAPI {
+  self := &DocAPI{opts: opts}
+  self.reset(path)
+  return self
+}
+
+// JournalErr reports, once, a failure to write the crash journal.
+// Journalling stops after a failure; Save still works.
+func (self *DocAPI) JournalErr() error {
+  if self.journal != nil && self.journal.err != nil {
+    err := self.journal.err
+    self.journal = nil
+    return err
+  }
+  return nil
+}
+
+// Close stops journalling. Unsaved edits stay in the journal.
+func (self *DocAPI) Close() {
+  self.journal.close()
+}
+
+func (self *DocAPI) prevNext() (*ParaAPI, *ParaAPI) {
+  prev := (*ParaAPI)(nil)
+  next := (*ParaAPI)(nil)
+
+  if self.node.Prev() != nil {
+    prev = self.node.Prev().Value.(*ParaAPI)
+  }
+
+  if self.node.Next() != nil {
+    next = self.node.Next().Value.(*ParaAPI)
+  }
+
+  return prev, next
+}
+
+func (self *DocAPI) check() {
+
+  discard := []*list.Element{}
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    para := e.Value.(*ParaAPI)
+    if e != self.node && para.IsEmpty() {
+      discard = append(discard, e)
+    }
+  }
+  for _, e := range discard {
+    self.list.Remove(e)
+    self.reordered()
+  }
+
+  if self.list.Len() == 0 {
+    self.list.PushFront(newPara(self))
+    self.reordered()
+  }
+
+  if self.node == nil {
+    self.node = self.list.Front()
+  }
+}
+
+// edit applies a mutation and records it in the undo history. Paragraphs
+// under the selection are captured before the edit can drop it.
+func (self *DocAPI) edit(merge interface{}, fn func()) {
+  spanned := self.spanned()
+  before := self.state(spanned...)
+  fn()
+  after := self.state(spanned...)
+  self.undo.record(merge, before, after)
+  self.journal.write(before, after)
+  if !before.equals(after) {
+    self.edited = true
+  }
+}
+
+// Undo reverts the last edit, reporting false if there is none.
+func (self *DocAPI) Undo() bool {
+  if state := self.undo.Undo(); state != nil {
+    // journal every paragraph the step restores, not just those near
+    // the cursor
+    spanned := parasOf(state.saved)
+    before := self.state(spanned...)
+    self.restore(state)
+    self.journal.write(before, self.state(spanned...))
+    self.edited = true
+    return true
+  }
+  return false
+}
+
+// Redo reapplies the last edit undone, reporting false if there is none.
+func (self *DocAPI) Redo() bool {
+  if state := self.undo.Redo(); state != nil {
+    spanned := parasOf(state.saved)
+    before := self.state(spanned...)
+    self.restore(state)
+    self.journal.write(before, self.state(spanned...))
+    self.edited = true
+    return true
+  }
+  return false
+}
+
+// Paragraph returns the paragraph under the cursor.
+func (self *DocAPI) Paragraph() *ParaAPI {
+  return self.node.Value.(*ParaAPI)
+}
+
+// All returns every paragraph in order, including an empty one the
+// cursor may be in.
+func (self *DocAPI) All() []*ParaAPI {
+  paras := []*ParaAPI{}
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    paras = append(paras, e.Value.(*ParaAPI))
+  }
+  return paras
+}
+
+// Seek moves the cursor to the start of one of the document's
+// paragraphs.
+func (self *DocAPI) Seek(para *ParaAPI) bool {
+  defer self.check()
+  self.Deselect()
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    if e.Value.(*ParaAPI) == para {
+      if e != self.node {
+        self.Paragraph().Clean()
+        self.node = e
+      }
+      para.Top()
+      return true
+    }
+  }
+  return false
+}
+
+// Path returns the file the document saves to, "" if it has none.
+func (self *DocAPI) Path() string {
+  return self.path
+}
+
+// ReSave saves the document to a new path, which it keeps for later
+// saves. On failure the old path stays.
+func (self *DocAPI) ReSave(path string) error {
+  prev := self.path
+  self.path = path
+  if err := self.write(); err != nil {
+    self.path = prev
+    return err
+  }
+  if prev != "" && prev != path {
+    os.Remove(journalPath(prev))
+  }
+  return nil
+}
+
+// Save writes the document to its file, keeping a backup of the old
+// copy, and starts a fresh journal. A document unchanged since it was
+// last saved or loaded is left alone, so an idle autosave neither
+// rewrites the file nor rotates out older backups.
+func (self *DocAPI) Save() error {
+
+  // no path, say after a failed load; work typed since must not look saved
+  if self.path == "" {
+    if self.edited {
+      return fmt.Errorf("no file name; use save <path>")
+    }
+    return nil
+  }
+
+  if !self.edited {
+    return nil
+  }
+  return self.write()
+}
+
+// write saves unconditionally to the document's path.
+func (self *DocAPI) write() error {
+
+  lines := []string{formatHeader()}
+  paras := []*ParaAPI{}
+
+  for _, key := range sortedKeys(self.meta) {
+    lines = append(lines, fmt.Sprintf("meta %s %s", escape(key), escape(self.meta[key])))
+  }
+
+  for _, key := range sortedKeys(self.vars) {
+    lines = append(lines, fmt.Sprintf("variable %s %s", escape(key), escape(self.vars[key])))
+  }
+
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    para := e.Value.(*ParaAPI)
+    for _, line := range para.Export() {
+      lines = append(lines, line)
+    }
+    paras = append(paras, para)
+  }
+
+  content := strings.Join(lines, "\n")
+
+  if err := backup(self.path, self.opts.Backups); err != nil {
+    return fmt.Errorf("backup %s: %v", self.path, err)
+  }
+  if err := writeAtomic(self.path, []byte(content)); err != nil {
+    return fmt.Errorf("save %s: %v", self.path, err)
+  }
+  self.edited = false
+
+  if !self.journaling {
+    return nil
+  }
+
+  // everything journalled so far is now in the file
+  self.journal.close()
+  journal, err := newJournal(self.path, journalHash([]byte(content)), paras)
+  self.journal = journal
+  if err != nil {
+    return fmt.Errorf("journal %s: %v", self.path, err)
+  }
+  return nil
+}
+
+// Load replaces the document with the file at path, replaying any
+// journal of unsaved edits. A file that cannot be read leaves an empty
+// document that will not autosave over it.
+func (self *DocAPI) Load(path string) error {
+
+  if path == "" {
+    path = "autosave.prose"
+  }
+
+  self.journal.close()
+  self.journal = nil
+  self.journaling = true
+
+  hash, paras, err := self.read(path)
+  if err != nil && !os.IsNotExist(err) {
+    // Never autosave over a file we could not understand.
+    self.path = ""
+    return fmt.Errorf("load %s: %v", path, err)
+  }
+
+  self.resume(hash, paras)
+  return nil
+}
+
+// reset empties the document, to be saved to path.
+func (self *DocAPI) reset(path string) {
+  self.list = list.New()
+  self.node = self.list.PushFront(newPara(self))
+  self.reordered()
+  self.path = path
+  self.vars = map[string]string{}
+  self.meta = map[string]string{}
+  self.undo = newUndo()
+  self.anchor = nil
+  self.edited = false
+}
+
+// read replaces the document with the content of path, without any of
+// the journalling Load sets up. It returns the hash of the file and its
+// paragraphs in file order. A missing file reads as an empty document.
+func (self *DocAPI) read(path string) (string, []*ParaAPI, error) {
+
+  self.reset(path)
+  defer self.check()
+
+  content, err := ioutil.ReadFile(path)
+  if err != nil {
+    return journalHash(nil), nil, err
+  }
+
+  lines := []string{}
+  scanner := bufio.NewScanner(strings.NewReader(string(content)))
+  scanner.Buffer(nil, len(content)+1)
+  for scanner.Scan() {
+    lines = append(lines, scanner.Text())
+  }
+
+  version, lines := formatDetect(lines)
+  lines, err = formatMigrate(version, lines)
+  if err != nil {
+    return "", nil, err
+  }
+  // an older format on disk needs saving even if nothing is typed
+  self.edited = version < formatVersion
+
+  paras := []*ParaAPI{}
+  para := []string{}
+  for _, line := range lines {
+    if strings.HasPrefix(line, "meta") || strings.HasPrefix(line, "variable") {
+      key, val, ok := keyValue(line)
+      if ok && strings.HasPrefix(line, "meta ") {
+        self.meta[key] = val
+      }
+      if ok && strings.HasPrefix(line, "variable ") {
+        self.vars[key] = val
+      }
+      continue
+    }
+    if strings.HasPrefix(line, "paragraph") {
+      if len(para) > 0 {
+        self.Paragraph().Import(para)
+      }
+      para = []string{line}
+      self.node = self.list.PushBack(newPara(self))
+      self.reordered()
+      paras = append(paras, self.Paragraph())
+      continue
+    }
+    para = append(para, line)
+  }
+  if len(para) > 0 {
+    self.Paragraph().Import(para)
+  }
+
+  return journalHash(content), paras, nil
+}
+
+// Up moves the cursor to the line above, into the paragraph before at
+// the top of this one, or opens an empty paragraph at the top of the
+// document.
+func (self *DocAPI) Up(layout Layout) bool {
+  defer self.check()
+  self.Deselect()
+
+  fpos := layout.Word(self.Paragraph().Word())
+  if self.Paragraph().Up(fpos, layout) {
+    return true
+  }
+
+  if self.node.Prev() != nil {
+    self.Paragraph().Clean()
+    self.node = self.node.Prev()
+    self.Paragraph().Bottom()
+    self.Paragraph().Up(fpos, layout)
+    return true
+  }
+  if !self.Paragraph().IsEmpty() {
+    self.Paragraph().Clean()
+    self.node = self.list.InsertBefore(newPara(self), self.node)
+    self.reordered()
+    return true
+  }
+  return false
+}
+
+// Down moves the cursor to the line below, into the paragraph after at
+// the bottom of this one, or opens an empty paragraph at the end of the
+// document.
+func (self *DocAPI) Down(layout Layout) bool {
+  defer self.check()
+  self.Deselect()
+
+  fpos := layout.Word(self.Paragraph().Word())
+  if self.Paragraph().Down(fpos, layout) {
+    return true
+  }
+
+  if self.node.Next() != nil {
+    self.Paragraph().Clean()
+    self.node = self.node.Next()
+    self.Paragraph().Top()
+    self.Paragraph().Down(fpos, layout)
+    return true
+  }
+  if !self.Paragraph().IsEmpty() {
+    self.Paragraph().Clean()
+    self.node = self.list.InsertAfter(newPara(self), self.node)
+    self.reordered()
+    return true
+  }
+  return false
+}
+
+// Left moves the cursor to the word before.
+func (self *DocAPI) Left() bool {
+  defer self.check()
+  self.Deselect()
+  return self.Paragraph().Left()
+}
+
+// Right moves the cursor to the word after.
+func (self *DocAPI) Right() bool {
+  defer self.check()
+  self.Deselect()
+  return self.Paragraph().Right()
+}
+
+// ShiftUp swaps the paragraph under the cursor with the one before.
+func (self *DocAPI) ShiftUp() bool {
+  defer self.check()
+  if self.node.Prev() != nil {
+    self.edit(nil, func() {
+      self.list.MoveBefore(self.node, self.node.Prev())
+      self.reordered()
+    })
+    return true
+  }
+  return false
+}
+
+// ShiftDown swaps the paragraph under the cursor with the one after.
+func (self *DocAPI) ShiftDown() bool {
+  defer self.check()
+  if self.node.Next() != nil {
+    self.edit(nil, func() {
+      self.list.MoveAfter(self.node, self.node.Next())
+      self.reordered()
+    })
+    return true
+  }
+  return false
+}
+
+// Return splits the paragraph at the cursor, which moves to the start
+// of the new one.
+func (self *DocAPI) Return() {
+  self.Deselect()
+  self.edit(nil, func() {
+    defer self.check()
+    prev := self.Paragraph()
+    self.node = self.list.InsertAfter(newPara(self), self.node)
+    self.reordered()
+    next := self.Paragraph()
+    prev.Split(next)
+    prev.Clean()
+    next.Clean()
+    next.Top()
+  })
+}
+
+// Insert types str at the caret. Consecutive inserts into one word
+// undo together.
+func (self *DocAPI) Insert(str string) {
+  self.Deselect()
+  self.edit(self.Paragraph().Word(), func() {
+    self.Paragraph().Insert(str)
+  })
+}
+
+// Space starts a new word after the cursor.
+func (self *DocAPI) Space() {
+  self.Deselect()
+  self.edit(nil, func() {
+    self.Paragraph().Space()
+  })
+}
+
+// BackSpace, Delete and DeleteWord delete the selection if there is one.
+func (self *DocAPI) BackSpace() {
+  if self.Selection() != nil {
+    self.edit(nil, func() {
+      self.deleteSelection()
+    })
+    return
+  }
+  self.edit(self.Paragraph().Word(), func() {
+    self.Paragraph().BackSpace()
+  })
+}
+
+// DeleteWord deletes the focused word whole, as BackSpace did before
+// words had a caret.
+func (self *DocAPI) DeleteWord() {
+  self.edit(nil, func() {
+    if !self.deleteSelection() {
+      self.Paragraph().DeleteWord()
+    }
+  })
+}
+
+// CaretLeft and CaretRight move the caret within the focused word;
+// Left and Right move between words.
+func (self *DocAPI) CaretLeft() bool {
+  self.Deselect()
+  return self.Paragraph().CaretLeft()
+}
+
+// CaretRight moves the caret on a character within the focused word.
+func (self *DocAPI) CaretRight() bool {
+  self.Deselect()
+  return self.Paragraph().CaretRight()
+}
+
+// Delete deletes the character after the caret. At the end of a
+// paragraph it joins the next one on.
+func (self *DocAPI) Delete() {
+  self.edit(nil, func() {
+    defer self.check()
+    if self.deleteSelection() {
+      return
+    }
+    if self.node.Next() != nil && self.Paragraph().IsEnd() && self.Paragraph().Word().IsCaretEnd() {
+      next := self.node.Next().Value.(*ParaAPI)
+      next.Top()
+      next.Split(self.Paragraph())
+      self.Paragraph().Right()
+      return
+    }
+    self.Paragraph().Delete()
+  })
+}
+
+// DQuote, Emphasis, Paren and Variable act on the whole selection when
+// there is one, setting the flag on every word unless all have it.
+func (self *DocAPI) DQuote() {
+  self.edit(nil, func() {
+    if !self.flagSelection(DQuote) {
+      self.Paragraph().DQuote()
+    }
+  })
+}
+
+// Period toggles a full stop after the focused word.
+func (self *DocAPI) Period() {
+  self.edit(nil, func() {
+    self.Paragraph().Period()
+  })
+}
+
+// Comma toggles a comma after the focused word.
+func (self *DocAPI) Comma() {
+  self.edit(nil, func() {
+    self.Paragraph().Comma()
+  })
+}
+
+// Exclaim toggles an exclamation mark after the focused word.
+func (self *DocAPI) Exclaim() {
+  self.edit(nil, func() {
+    self.Paragraph().Exclaim()
+  })
+}
+
+// Question toggles a question mark after the focused word.
+func (self *DocAPI) Question() {
+  self.edit(nil, func() {
+    self.Paragraph().Question()
+  })
+}
+
+// Hyphen toggles a hyphen after the focused word.
+func (self *DocAPI) Hyphen() {
+  self.edit(nil, func() {
+    self.Paragraph().Hyphen()
+  })
+}
+
+// Emphasis toggles emphasis on the selection or the focused word.
+func (self *DocAPI) Emphasis() {
+  self.edit(nil, func() {
+    if !self.flagSelection(Emphasis) {
+      self.Paragraph().Emphasis()
+    }
+  })
+}
+
+// UCFirst capitalises every selected word, or the focused one.
+func (self *DocAPI) UCFirst() {
+  self.edit(nil, func() {
+    if words := self.Selection(); words != nil {
+      for _, word := range words {
+        word.UCFirst()
+      }
+      return
+    }
+    self.Paragraph().UCFirst()
+  })
+}
+
+// Heading makes the paragraph under the cursor a heading, or content if
+// it is one.
+func (self *DocAPI) Heading() {
+  self.edit(nil, func() {
+    self.Paragraph().Heading()
+  })
+}
+
+// Bullet makes the paragraph under the cursor a bullet point, or
+// content if it is one.
+func (self *DocAPI) Bullet() {
+  self.edit(nil, func() {
+    self.Paragraph().Bullet()
+  })
+}
+
+// Paren toggles parentheses on the selection or the focused word.
+func (self *DocAPI) Paren() {
+  self.edit(nil, func() {
+    if !self.flagSelection(Paren) {
+      self.Paragraph().Paren()
+    }
+  })
+}
+
+// Colon toggles a colon after the focused word.
+func (self *DocAPI) Colon() {
+  self.edit(nil, func() {
+    self.Paragraph().Colon()
+  })
+}
+
+// SemiColon toggles a semicolon after the focused word.
+func (self *DocAPI) SemiColon() {
+  self.edit(nil, func() {
+    self.Paragraph().SemiColon()
+  })
+}
+
+// Home moves the cursor to the start of its line.
+func (self *DocAPI) Home(layout Layout) {
+  self.Deselect()
+  self.Paragraph().Home(layout)
+}
+
+// End moves the cursor to the end of its line.
+func (self *DocAPI) End(layout Layout) {
+  self.Deselect()
+  self.Paragraph().End(layout)
+}
+
+// Top moves the cursor to the first word of its paragraph.
+func (self *DocAPI) Top() {
+  self.Deselect()
+  self.Paragraph().Top()
+}
+
+// Bottom moves the cursor to the last word of its paragraph.
+func (self *DocAPI) Bottom() {
+  self.Deselect()
+  self.Paragraph().Bottom()
+}
+
+// WordList returns the distinct words of at least min characters in
+// the whole document, sorted, for autocompletion.
+func (self *DocAPI) WordList(min int) []string {
+  words := map[string]struct{}{}
+
+  recv := workerpool.New(1)
+  pool := workerpool.New(8)
+
+  stream := make(chan string, 100)
+  recv.Job(func() {
+    for word := range stream {
+      words[word] = struct{}{}
+    }
+  })
+
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    para := e.Value.(*ParaAPI)
+    if !para.IsEmpty() {
+      pool.Job(func() {
+        for _, word := range para.WordList(min) {
+          stream <- word
+        }
+      })
+    }
+  }
+
+  pool.Wait()
+  close(stream)
+  recv.Wait()
+
+  list := []string{}
+  for word, _ := range words {
+    list = append(list, word)
+  }
+  sort.Strings(list)
+
+  return list
+}
+
+// Get returns the value of a variable, or def if it is not set.
+func (self *DocAPI) Get(name string, def string) string {
+  if val, ok := self.vars[name]; ok {
+    return val
+  }
+  return def
+}
+
+// Set sets a variable as an undoable edit.
+func (self *DocAPI) Set(name string, val string) {
+  self.edit(nil, func() {
+    self.vars[name] = val
+  })
+}
+
+// Drop removes a variable as an undoable edit, reporting false if it
+// was not set.
+func (self *DocAPI) Drop(name string) bool {
+  if _, ok := self.vars[name]; ok {
+    self.edit(nil, func() {
+      delete(self.vars, name)
+    })
+    return true
+  }
+  return false
+}
+
+// Variables returns the names of all variables, sorted.
+func (self *DocAPI) Variables() []string {
+  return sortedKeys(self.vars)
+}
+
+// Exists reports whether a variable is set.
+func (self *DocAPI) Exists(name string) bool {
+  _, ok := self.vars[name]
+  return ok
+}
+
+// Meta returns a metadata field such as title or language, or "".
+func (self *DocAPI) Meta(name string) string {
+  return self.meta[name]
+}
+
+// SetMeta sets a metadata field, or removes it when val is "", as an
+// undoable edit.
+func (self *DocAPI) SetMeta(name string, val string) {
+  self.edit(nil, func() {
+    if val == "" {
+      delete(self.meta, name)
+      return
+    }
+    self.meta[name] = val
+  })
+}
+
+func sortedKeys(m map[string]string) []string {
+  keys := []string{}
+  for key, _ := range m {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+  return keys
+}
+
+// Variable toggles whether the selection or the focused word names a
+// variable.
+func (self *DocAPI) Variable() {
+  self.edit(nil, func() {
+    if !self.flagSelection(Variable) {
+      self.Paragraph().Variable()
+    }
+  })
+}
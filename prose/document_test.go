@@ -0,0 +1,172 @@
+package prose
+
+import (
+  "fmt"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+// lines renders every paragraph as Save would, so two documents compare
+// equal only if they would write the same file.
+func lines(doc *DocAPI) string {
+  out := []string{}
+  for _, key := range sortedKeys(doc.meta) {
+    out = append(out, "meta "+key+" "+doc.meta[key])
+  }
+  for _, key := range sortedKeys(doc.vars) {
+    out = append(out, "variable "+key+" "+doc.vars[key])
+  }
+  for _, para := range doc.Paragraphs() {
+    out = append(out, para.Export()...)
+  }
+  return strings.Join(out, "\n")
+}
+
+// sample builds a small document using most of what the format can hold:
+// every style, punctuation, quotes, emphasis, a variable, and text that
+// needs escaping.
+func sample(t *testing.T) *DocAPI {
+  doc := Create(filepath.Join(t.TempDir(), "sample.prose"), DefaultOptions())
+  doc.SetMeta("title", "Tests, Mostly")
+  doc.SetMeta("author", "A. N. Other")
+
+  lines := []struct {
+    style int
+    text  string
+  }{
+    {Heading, "Chapter One"},
+    {Content, `"Hello," she said, *quietly*. It cost 100% (or so) of name's savings; fine?`},
+    {Bullet, "first item"},
+    {Bullet, "second item!"},
+    {Content, "The end..."},
+  }
+  paras := []*ParaAPI{}
+  for _, line := range lines {
+    para := newPara(doc)
+    para.style = line.style
+    para.Tokenize(line.text, true)
+    paras = append(paras, para)
+  }
+  doc.Splice(paras)
+
+  for _, word := range doc.Paragraphs()[1].Words() {
+    if word.Text() == "name's" {
+      word.text = "name"
+      word.Set(Variable)
+    }
+  }
+  doc.Set("name", "Ada, Countess")
+  return doc
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+  doc := sample(t)
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+
+  back, err := Open(doc.Path(), DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got, want := lines(back), lines(doc); got != want {
+    t.Errorf("loaded\n%s\nwant\n%s", got, want)
+  }
+  if got := back.Get("name", ""); got != "Ada, Countess" {
+    t.Errorf("variable %q, want %q", got, "Ada, Countess")
+  }
+  if got := back.Meta("title"); got != "Tests, Mostly" {
+    t.Errorf("title %q, want %q", got, "Tests, Mostly")
+  }
+
+  // saving what was loaded writes the same bytes
+  again := filepath.Join(t.TempDir(), "again.prose")
+  if err := back.ReSave(again); err != nil {
+    t.Fatal(err)
+  }
+  first, _ := ioutil.ReadFile(doc.Path())
+  second, _ := ioutil.ReadFile(again)
+  if string(first) != string(second) {
+    t.Errorf("resaved file differs\n%s\nwant\n%s", second, first)
+  }
+}
+
+func TestLoadVersion1(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "old.prose")
+  v1 := strings.Join([]string{
+    "variable name Ada, Countess of 100%",
+    "paragraph heading",
+    "0,Chapter",
+    "paragraph",
+    "1,a,b",
+    "0,100%",
+    "2048,name",
+  }, "\n")
+  if err := ioutil.WriteFile(path, []byte(v1), 0644); err != nil {
+    t.Fatal(err)
+  }
+
+  doc, err := NewDoc(path, DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := doc.Get("name", ""); got != "Ada, Countess of 100%" {
+    t.Errorf("variable %q", got)
+  }
+  paras := doc.Paragraphs()
+  if len(paras) != 2 || paras[0].Style() != Heading {
+    t.Fatalf("loaded %d paragraphs", len(paras))
+  }
+  if got, want := paras[1].Plain(), `"a,b" 100% Ada, Countess of 100%`; got != want {
+    t.Errorf("paragraph %q, want %q", got, want)
+  }
+
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+  content, _ := ioutil.ReadFile(path)
+  if !strings.HasPrefix(string(content), formatHeader()+"\n") {
+    t.Errorf("saved without the current header:\n%s", content)
+  }
+}
+
+func TestLoadNewerVersion(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "new.prose")
+  future := fmt.Sprintf("%s %d\nparagraph\n0,word", formatMagic, formatVersion+1)
+  if err := ioutil.WriteFile(path, []byte(future), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := Open(path, DefaultOptions()); err == nil {
+    t.Error("opened a file from a newer version")
+  }
+}
+
+// An empty variable escapes to nothing, and must still come back from
+// the file and from the journal.
+func TestEmptyVariable(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "empty.prose")
+  doc, err := NewDoc(path, DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+  typeParas(doc, "words")
+  doc.Set("empty", "")
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+
+  back, err := Open(path, DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+  if !back.Exists("empty") || back.Get("empty", "unset") != "" {
+    t.Errorf("saved empty variable read back as %q", back.Get("empty", "unset"))
+  }
+
+  doc.Set("blank", "")
+  if back := crash(t, path); !back.Exists("blank") || !back.Exists("empty") {
+    t.Errorf("journalled empty variable lost, have %v", back.Variables())
+  }
+}
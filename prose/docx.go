@@ -0,0 +1,388 @@
+package prose
+
+import (
+  "bytes"
+  "encoding/xml"
+  "errors"
+  "fmt"
+  "html"
+  "io"
+  "strings"
+  "time"
+)
+
+const (
+  docxMain = `http://schemas.openxmlformats.org/wordprocessingml/2006/main`
+  docxRels = `http://schemas.openxmlformats.org/officeDocument/2006/relationships`
+)
+
+var docxParts = []zipEntry{
+  {name: "[Content_Types].xml", body: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>
+<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>
+</Types>
+`},
+  {name: "_rels/.rels", body: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>
+</Relationships>
+`},
+  {name: "word/_rels/document.xml.rels", body: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>
+</Relationships>
+`},
+  {name: "word/styles.xml", body: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="` + docxMain + `">
+<w:docDefaults>
+<w:rPrDefault><w:rPr><w:sz w:val="24"/><w:szCs w:val="24"/><w:lang w:val="en-GB"/></w:rPr></w:rPrDefault>
+<w:pPrDefault><w:pPr><w:spacing w:after="160" w:line="360" w:lineRule="auto"/></w:pPr></w:pPrDefault>
+</w:docDefaults>
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal">
+<w:name w:val="Normal"/>
+<w:qFormat/>
+</w:style>
+<w:style w:type="paragraph" w:styleId="Heading1">
+<w:name w:val="heading 1"/>
+<w:basedOn w:val="Normal"/>
+<w:next w:val="Normal"/>
+<w:qFormat/>
+<w:pPr><w:keepNext/><w:spacing w:before="480" w:after="240"/><w:outlineLvl w:val="0"/></w:pPr>
+<w:rPr><w:b/><w:sz w:val="32"/><w:szCs w:val="32"/></w:rPr>
+</w:style>
+<w:style w:type="paragraph" w:styleId="ListBullet">
+<w:name w:val="List Bullet"/>
+<w:basedOn w:val="Normal"/>
+<w:qFormat/>
+<w:pPr><w:numPr><w:numId w:val="1"/></w:numPr><w:spacing w:after="0"/><w:contextualSpacing/></w:pPr>
+</w:style>
+</w:styles>
+`},
+  {name: "word/numbering.xml", body: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering xmlns:w="` + docxMain + `">
+<w:abstractNum w:abstractNumId="0">
+<w:multiLevelType w:val="singleLevel"/>
+<w:lvl w:ilvl="0">
+<w:start w:val="1"/>
+<w:numFmt w:val="bullet"/>
+<w:lvlText w:val="•"/>
+<w:lvlJc w:val="left"/>
+<w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr>
+</w:lvl>
+</w:abstractNum>
+<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>
+</w:numbering>
+`},
+}
+
+// exportDOCX writes an Office Open XML package using the built in
+// Heading 1 and List Bullet styles so publishers' templates apply.
+func exportDOCX(doc *DocAPI) ([]byte, error) {
+
+  body := []string{}
+  for _, para := range doc.Paragraphs() {
+    props := ""
+    switch para.style {
+    case Heading:
+      props = `<w:pPr><w:pStyle w:val="Heading1"/></w:pPr>`
+    case Bullet:
+      props = `<w:pPr><w:pStyle w:val="ListBullet"/><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr>`
+    }
+    body = append(body, "<w:p>"+props+para.DOCX()+"</w:p>")
+  }
+
+  document := strings.Join([]string{
+    `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`,
+    `<w:document xmlns:w="` + docxMain + `" xmlns:r="` + docxRels + `">`,
+    `<w:body>`,
+    strings.Join(body, "\n"),
+    `<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr>`,
+    `</w:body>`,
+    `</w:document>`,
+    ``,
+  }, "\n")
+
+  now := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+  core := []string{
+    `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`,
+    `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`,
+    fmt.Sprintf(`<dc:title>%s</dc:title>`, html.EscapeString(doc.Title())),
+  }
+  if author := doc.Meta("author"); author != "" {
+    core = append(core, fmt.Sprintf(`<dc:creator>%s</dc:creator>`, html.EscapeString(author)))
+  }
+  core = append(core,
+    fmt.Sprintf(`<dcterms:created xsi:type="dcterms:W3CDTF">%s</dcterms:created>`, now),
+    fmt.Sprintf(`<dcterms:modified xsi:type="dcterms:W3CDTF">%s</dcterms:modified>`, now),
+    `</cp:coreProperties>`,
+    ``,
+  )
+
+  parts := append([]zipEntry{}, docxParts...)
+  parts = append(parts,
+    zipEntry{name: "word/document.xml", body: document},
+    zipEntry{name: "docProps/core.xml", body: strings.Join(core, "\n")},
+  )
+  return writeZip(parts)
+}
+
+// DOCX renders the paragraph as runs, merging neighbouring words that
+// share the same italic setting.
+func (self *ParaAPI) DOCX() string {
+
+  words := self.Words()
+  runs := []string{}
+  text := ""
+  italic := false
+
+  flush := func() {
+    if text == "" {
+      return
+    }
+    props := ""
+    if italic {
+      props = "<w:rPr><w:i/><w:iCs/></w:rPr>"
+    }
+    runs = append(runs, fmt.Sprintf(`<w:r>%s<w:t xml:space="preserve">%s</w:t></w:r>`, props, htmlEscaper.Replace(text)))
+    text = ""
+  }
+
+  for i, word := range words {
+    prev, next := neighbours(words, i)
+    prefix, suffix, gap := word.Affixes(prev, next)
+
+    if word.IsEmphasis() != italic {
+      flush()
+      italic = word.IsEmphasis()
+    }
+    text += prefix + word.Display() + suffix
+    if next == nil {
+      break
+    }
+    if italic && !next.IsEmphasis() {
+      flush()
+      italic = false
+    }
+    text += gap
+  }
+  flush()
+
+  return strings.Join(runs, "")
+}
+
+type docxStyle struct {
+  name     string
+  based    string
+  heading  bool
+  list     bool
+  emphasis bool
+}
+
+// docxOn reads a toggle property such as <w:i/> or <w:b w:val="0"/>.
+func docxOn(e xml.StartElement) bool {
+  switch xmlAttr(e, "val") {
+  case "0", "false", "off", "none":
+    return false
+  }
+  return true
+}
+
+// docxStyles reads paragraph and character styles, folding in what each
+// inherits through basedOn.
+func docxStyles(data []byte) map[string]*docxStyle {
+
+  styles := map[string]*docxStyle{}
+  decoder := xml.NewDecoder(bytes.NewReader(data))
+  style := (*docxStyle)(nil)
+  inRun := false
+
+  for {
+    token, err := decoder.Token()
+    if err != nil {
+      break
+    }
+    switch e := token.(type) {
+    case xml.StartElement:
+      if e.Name.Space != docxMain {
+        continue
+      }
+      switch e.Name.Local {
+      case "style":
+        style = &docxStyle{}
+        styles[xmlAttr(e, "styleId")] = style
+      case "name":
+        if style != nil {
+          style.name = strings.ToLower(xmlAttr(e, "val"))
+        }
+      case "basedOn":
+        if style != nil {
+          style.based = xmlAttr(e, "val")
+        }
+      case "outlineLvl":
+        if style != nil {
+          style.heading = true
+        }
+      case "numPr":
+        if style != nil {
+          style.list = true
+        }
+      case "rPr":
+        inRun = true
+      case "i", "b":
+        if style != nil && inRun {
+          style.emphasis = docxOn(e)
+        }
+      }
+    case xml.EndElement:
+      switch e.Name.Local {
+      case "style":
+        style = nil
+      case "rPr":
+        inRun = false
+      }
+    }
+  }
+
+  for _, style := range styles {
+    names := []string{style.name}
+    seen := map[*docxStyle]bool{style: true}
+    for parent := styles[style.based]; parent != nil && !seen[parent]; parent = styles[parent.based] {
+      seen[parent] = true
+      style.heading = style.heading || parent.heading
+      style.list = style.list || parent.list
+      style.emphasis = style.emphasis || parent.emphasis
+      names = append(names, parent.name)
+    }
+    for _, name := range names {
+      style.heading = style.heading || strings.HasPrefix(name, "heading") || name == "title"
+      style.list = style.list || strings.HasPrefix(name, "list")
+    }
+  }
+  return styles
+}
+
+// importDOCX reads word/document.xml. Heading styles and outline levels
+// become Heading, numbered or list styled paragraphs Bullet, and italic
+// or bold runs Emphasis.
+func importDOCX(doc *DocAPI, content []byte) (*imported, error) {
+
+  files, err := readZip(content)
+  if err != nil {
+    return nil, err
+  }
+  body, ok := files["word/document.xml"]
+  if !ok {
+    return nil, errors.New("no word/document.xml in package")
+  }
+  styles := docxStyles(files["word/styles.xml"])
+
+  paras := []*ParaAPI{}
+  stack := []*richText{}
+  heading, list := false, false
+  inPara, inRun, inText := false, false, false
+  emphasis := false
+
+  decoder := xml.NewDecoder(bytes.NewReader(body))
+  for {
+    token, err := decoder.Token()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return nil, err
+    }
+
+    text := (*richText)(nil)
+    if len(stack) > 0 {
+      text = stack[len(stack)-1]
+    }
+
+    switch e := token.(type) {
+
+    case xml.StartElement:
+      if e.Name.Space != docxMain {
+        continue
+      }
+      switch e.Name.Local {
+      case "p":
+        stack = append(stack, &richText{style: Content})
+        heading, list = false, false
+      case "pPr":
+        inPara = true
+      case "pStyle":
+        if style := styles[xmlAttr(e, "val")]; style != nil {
+          heading = heading || style.heading
+          list = list || style.list
+        }
+      case "outlineLvl":
+        heading = heading || inPara && xmlAttr(e, "val") != "9"
+      case "numId":
+        list = list || inPara && xmlAttr(e, "val") != "0"
+      case "r":
+        inRun = true
+        emphasis = false
+      case "rStyle":
+        if style := styles[xmlAttr(e, "val")]; style != nil && inRun {
+          emphasis = emphasis || style.emphasis
+        }
+      case "i", "b":
+        if inRun && !inPara {
+          emphasis = docxOn(e)
+        }
+      case "t":
+        inText = inRun
+      case "tab", "br", "cr":
+        if text != nil && inRun {
+          text.Add(" ", emphasis)
+        }
+      case "noBreakHyphen":
+        if text != nil && inRun {
+          text.Add("-", emphasis)
+        }
+      }
+
+    case xml.EndElement:
+      if e.Name.Space != docxMain {
+        continue
+      }
+      switch e.Name.Local {
+      case "pPr":
+        inPara = false
+        if text != nil {
+          switch {
+          case heading:
+            text.style = Heading
+          case list:
+            text.style = Bullet
+          }
+        }
+      case "r":
+        inRun = false
+      case "t":
+        inText = false
+      case "p":
+        if text == nil {
+          continue
+        }
+        stack = stack[:len(stack)-1]
+        if para := text.Para(doc); !para.IsEmpty() {
+          paras = append(paras, para)
+        }
+      }
+
+    case xml.CharData:
+      if text != nil && inText {
+        text.Add(string(e), emphasis)
+      }
+    }
+  }
+
+  return &imported{paras: paras}, nil
+}
@@ -0,0 +1,148 @@
+package prose
+
+import (
+  "crypto/sha1"
+  "fmt"
+  "html"
+  "strings"
+  "time"
+)
+
+type epubChapter struct {
+  title string
+  paras []*ParaAPI
+}
+
+const epubStyle = `body { font-family: serif; line-height: 1.5; }
+h1 { font-weight: normal; text-align: center; margin: 2em 0 1em; }
+p { margin: 0; text-indent: 1.5em; }
+h1 + p, ul + p { text-indent: 0; }
+.paren { color: gray; }
+`
+
+// epubChapters splits the document at each Heading. Anything before
+// the first heading becomes a chapter named after the document.
+func epubChapters(doc *DocAPI) []*epubChapter {
+  chapters := []*epubChapter{}
+  for _, para := range doc.Paragraphs() {
+    if para.style == Heading || len(chapters) == 0 {
+      title := doc.Title()
+      if para.style == Heading {
+        title = para.Plain()
+      }
+      chapters = append(chapters, &epubChapter{title: title})
+    }
+    last := chapters[len(chapters)-1]
+    last.paras = append(last.paras, para)
+  }
+  return chapters
+}
+
+func epubXHTML(title string, lang string, body []string) string {
+  out := []string{
+    `<?xml version="1.0" encoding="utf-8"?>`,
+    `<!DOCTYPE html>`,
+    fmt.Sprintf(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%s" lang="%s">`, lang, lang),
+    `<head>`,
+    fmt.Sprintf(`<title>%s</title>`, html.EscapeString(title)),
+    `<link rel="stylesheet" type="text/css" href="style.css"/>`,
+    `</head>`,
+    `<body>`,
+  }
+  out = append(out, body...)
+  out = append(out, `</body>`, `</html>`, ``)
+  return strings.Join(out, "\n")
+}
+
+// epubIdentifier is stable for a given title and author so re-exports
+// replace the book on a reader rather than duplicating it.
+func epubIdentifier(doc *DocAPI) string {
+  if id := doc.Meta("identifier"); id != "" {
+    return id
+  }
+  sum := sha1.Sum([]byte(doc.Title() + "\x00" + doc.Meta("author")))
+  sum[6] = (sum[6] & 0x0f) | 0x50
+  sum[8] = (sum[8] & 0x3f) | 0x80
+  return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func exportEPUB(doc *DocAPI) ([]byte, error) {
+
+  lang := doc.Meta("language")
+  if lang == "" {
+    lang = "en"
+  }
+  lang = html.EscapeString(lang)
+
+  chapters := epubChapters(doc)
+  if len(chapters) == 0 {
+    chapters = append(chapters, &epubChapter{title: doc.Title()})
+  }
+
+  files := []zipEntry{
+    {name: "META-INF/container.xml", body: strings.Join([]string{
+      `<?xml version="1.0" encoding="utf-8"?>`,
+      `<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">`,
+      `<rootfiles>`,
+      `<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>`,
+      `</rootfiles>`,
+      `</container>`,
+      ``,
+    }, "\n")},
+    {name: "OEBPS/style.css", body: epubStyle},
+  }
+
+  manifest := []string{
+    `<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>`,
+    `<item id="style" href="style.css" media-type="text/css"/>`,
+  }
+  spine := []string{}
+  toc := []string{}
+
+  for i, chapter := range chapters {
+    id := fmt.Sprintf("chapter%d", i+1)
+    name := id + ".xhtml"
+    files = append(files, zipEntry{name: "OEBPS/" + name, body: epubXHTML(chapter.title, lang, htmlBlocks(chapter.paras))})
+    manifest = append(manifest, fmt.Sprintf(`<item id="%s" href="%s" media-type="application/xhtml+xml"/>`, id, name))
+    spine = append(spine, fmt.Sprintf(`<itemref idref="%s"/>`, id))
+    toc = append(toc, fmt.Sprintf(`<li><a href="%s">%s</a></li>`, name, html.EscapeString(chapter.title)))
+  }
+
+  nav := []string{`<nav epub:type="toc" id="toc">`, `<h1>Contents</h1>`, `<ol>`}
+  nav = append(nav, toc...)
+  nav = append(nav, `</ol>`, `</nav>`)
+
+  files = append(files, zipEntry{name: "OEBPS/nav.xhtml", body: epubXHTML("Contents", lang, nav)})
+
+  metadata := []string{
+    `<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">`,
+    fmt.Sprintf(`<dc:identifier id="bookid">%s</dc:identifier>`, html.EscapeString(epubIdentifier(doc))),
+    fmt.Sprintf(`<dc:title>%s</dc:title>`, html.EscapeString(doc.Title())),
+    fmt.Sprintf(`<dc:language>%s</dc:language>`, lang),
+  }
+  if author := doc.Meta("author"); author != "" {
+    metadata = append(metadata, fmt.Sprintf(`<dc:creator>%s</dc:creator>`, html.EscapeString(author)))
+  }
+  metadata = append(metadata,
+    fmt.Sprintf(`<meta property="dcterms:modified">%s</meta>`, time.Now().UTC().Format("2006-01-02T15:04:05Z")),
+    `</metadata>`,
+  )
+
+  opf := []string{
+    `<?xml version="1.0" encoding="utf-8"?>`,
+    `<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid">`,
+  }
+  opf = append(opf, metadata...)
+  opf = append(opf, `<manifest>`)
+  opf = append(opf, manifest...)
+  opf = append(opf, `</manifest>`, `<spine>`)
+  opf = append(opf, spine...)
+  opf = append(opf, `</spine>`, `</package>`, ``)
+
+  files = append(files, zipEntry{name: "OEBPS/content.opf", body: strings.Join(opf, "\n")})
+
+  // the mimetype must come first and be stored uncompressed
+  files = append([]zipEntry{{name: "mimetype", body: "application/epub+zip", store: true}}, files...)
+
+  return writeZip(files)
+}
@@ -0,0 +1,117 @@
+package prose
+
+import (
+  "archive/zip"
+  "bytes"
+  "fmt"
+  "sort"
+  "strings"
+  "time"
+)
+
+// exporters render a whole document into a named output format.
+var exporters = map[string]func(*DocAPI) ([]byte, error){
+  "md":       exportMarkdown,
+  "html":     exportHTML,
+  "epub":     exportEPUB,
+  "pdf":      exportPDF,
+  "docx":     exportDOCX,
+  "tex":      exportLaTeX,
+  "fountain": exportFountain,
+  "json":     exportJSON,
+}
+
+// ExportFormats lists the names Export accepts, sorted.
+func ExportFormats() []string {
+  names := []string{}
+  for name, _ := range exporters {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+// Export writes the document to path in one of ExportFormats.
+func (self *DocAPI) Export(format string, path string) error {
+  export, ok := exporters[format]
+  if !ok {
+    return fmt.Errorf("unknown export format: %s", format)
+  }
+  content, err := export(self)
+  if err != nil {
+    return fmt.Errorf("export %s: %v", format, err)
+  }
+  if err := writeAtomic(path, content); err != nil {
+    return fmt.Errorf("export %s: %v", format, err)
+  }
+  return nil
+}
+
+// Paragraphs returns the non-empty paragraphs in order.
+func (self *DocAPI) Paragraphs() []*ParaAPI {
+  paras := []*ParaAPI{}
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    para := e.Value.(*ParaAPI)
+    if !para.IsEmpty() {
+      paras = append(paras, para)
+    }
+  }
+  return paras
+}
+
+func neighbours(words []*WordAPI, i int) (*WordAPI, *WordAPI) {
+  prev := (*WordAPI)(nil)
+  next := (*WordAPI)(nil)
+  if i > 0 {
+    prev = words[i-1]
+  }
+  if i < len(words)-1 {
+    next = words[i+1]
+  }
+  return prev, next
+}
+
+// Plain renders the paragraph as text, exactly as the editor shows it.
+func (self *ParaAPI) Plain() string {
+  words := self.Words()
+  parts := []string{}
+  for i, word := range words {
+    prev, next := neighbours(words, i)
+    prefix, suffix, gap := word.Affixes(prev, next)
+    parts = append(parts, prefix+word.Display()+suffix+gap)
+  }
+  return strings.TrimSpace(strings.Join(parts, ""))
+}
+
+type zipEntry struct {
+  name  string
+  body  string
+  store bool
+}
+
+// writeZip builds a package such as EPUB or DOCX, entries in order.
+func writeZip(entries []zipEntry) ([]byte, error) {
+
+  buf := &bytes.Buffer{}
+  archive := zip.NewWriter(buf)
+
+  for _, entry := range entries {
+    header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+    if entry.store {
+      header.Method = zip.Store
+    }
+    header.SetModTime(time.Now())
+    w, err := archive.CreateHeader(header)
+    if err != nil {
+      return nil, err
+    }
+    if _, err := w.Write([]byte(entry.body)); err != nil {
+      return nil, err
+    }
+  }
+
+  if err := archive.Close(); err != nil {
+    return nil, err
+  }
+  return buf.Bytes(), nil
+}
@@ -0,0 +1,181 @@
+package prose
+
+import (
+  "bytes"
+  "encoding/json"
+  "encoding/xml"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "testing"
+)
+
+// plain renders paragraphs as text, one per entry, styles aside.
+func plain(paras []*ParaAPI) []string {
+  out := []string{}
+  for _, para := range paras {
+    out = append(out, para.Plain())
+  }
+  return out
+}
+
+func styles(paras []*ParaAPI) []int {
+  out := []int{}
+  for _, para := range paras {
+    out = append(out, para.Style())
+  }
+  return out
+}
+
+// reimport reads an export back through one of the importers.
+func reimport(t *testing.T, format string, content []byte) []*ParaAPI {
+  doc := Create(filepath.Join(t.TempDir(), "reimport.prose"), DefaultOptions())
+  in, err := importers[format](doc, content)
+  if err != nil {
+    t.Fatalf("%s import: %v", format, err)
+  }
+  for key, val := range in.vars {
+    doc.vars[key] = val
+  }
+  return in.paras
+}
+
+func sameText(t *testing.T, format string, got []string, want []string) {
+  if strings.Join(got, "\n") != strings.Join(want, "\n") {
+    t.Errorf("%s round trip\ngot:\n%s\nwant:\n%s", format, strings.Join(got, "\n"), strings.Join(want, "\n"))
+  }
+}
+
+func parseXML(name string, content []byte, html bool) error {
+  decoder := xml.NewDecoder(bytes.NewReader(content))
+  if html {
+    decoder.Strict = false
+    decoder.AutoClose = xml.HTMLAutoClose
+    decoder.Entity = xml.HTMLEntity
+  }
+  for {
+    _, err := decoder.Token()
+    if err == io.EOF {
+      return nil
+    }
+    if err != nil {
+      return fmt.Errorf("%s: %v", name, err)
+    }
+  }
+}
+
+// exportChecks read each export format back, as far as the format
+// allows without a third-party parser.
+var exportChecks = map[string]func(t *testing.T, doc *DocAPI, content []byte){
+
+  "md": func(t *testing.T, doc *DocAPI, content []byte) {
+    paras := reimport(t, "md", content)
+    sameText(t, "md", plain(paras), plain(doc.Paragraphs()))
+    if fmt.Sprint(styles(paras)) != fmt.Sprint(styles(doc.Paragraphs())) {
+      t.Errorf("md styles %v, want %v", styles(paras), styles(doc.Paragraphs()))
+    }
+  },
+
+  "html": func(t *testing.T, doc *DocAPI, content []byte) {
+    if err := parseXML("html", content, true); err != nil {
+      t.Error(err)
+    }
+    if !bytes.Contains(content, []byte("<h1>Chapter One</h1>")) {
+      t.Error("html export has no heading")
+    }
+  },
+
+  "epub": func(t *testing.T, doc *DocAPI, content []byte) {
+    files, err := readZip(content)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if string(files["mimetype"]) != "application/epub+zip" {
+      t.Errorf("epub mimetype %q", files["mimetype"])
+    }
+    for name, data := range files {
+      if name != "mimetype" {
+        if err := parseXML(name, data, false); err != nil {
+          t.Error(err)
+        }
+      }
+    }
+  },
+
+  "pdf": func(t *testing.T, doc *DocAPI, content []byte) {
+    str := string(content)
+    if !strings.HasPrefix(str, "%PDF-") || !strings.HasSuffix(strings.TrimSpace(str), "%%EOF") {
+      t.Fatal("pdf export lacks a header or trailer")
+    }
+    // the trailer must point at the cross-reference table
+    fields := strings.Fields(str[strings.LastIndex(str, "startxref"):])
+    offset, err := strconv.Atoi(fields[1])
+    if err != nil || offset >= len(str) || !strings.HasPrefix(str[offset:], "xref") {
+      t.Errorf("pdf startxref %q does not point at xref", fields[1])
+    }
+  },
+
+  "docx": func(t *testing.T, doc *DocAPI, content []byte) {
+    paras := reimport(t, "docx", content)
+    sameText(t, "docx", plain(paras), plain(doc.Paragraphs()))
+    if fmt.Sprint(styles(paras)) != fmt.Sprint(styles(doc.Paragraphs())) {
+      t.Errorf("docx styles %v, want %v", styles(paras), styles(doc.Paragraphs()))
+    }
+  },
+
+  "tex": func(t *testing.T, doc *DocAPI, content []byte) {
+    str := string(content)
+    if !strings.Contains(str, `\begin{document}`) || !strings.HasSuffix(strings.TrimSpace(str), `\end{document}`) {
+      t.Error("tex export lacks a document environment")
+    }
+    depth := 0
+    for i, r := range str {
+      if r == '{' && (i == 0 || str[i-1] != '\\') {
+        depth++
+      }
+      if r == '}' && (i == 0 || str[i-1] != '\\') {
+        depth--
+      }
+      if depth < 0 {
+        t.Fatalf("tex export closes an unopened brace at %d", i)
+      }
+    }
+    if depth != 0 {
+      t.Errorf("tex export leaves %d braces open", depth)
+    }
+  },
+
+  "fountain": func(t *testing.T, doc *DocAPI, content []byte) {
+    // a screenplay has no bullet points
+    sameText(t, "fountain", plain(reimport(t, "fountain", content)), plain(doc.Paragraphs()))
+  },
+
+  "json": func(t *testing.T, doc *DocAPI, content []byte) {
+    if !json.Valid(content) {
+      t.Fatal("json export is not valid JSON")
+    }
+    sameText(t, "json", plain(reimport(t, "json", content)), plain(doc.Paragraphs()))
+  },
+}
+
+func TestExportParses(t *testing.T) {
+  doc := sample(t)
+  if _, err := os.Stat(doc.opts.Font); err != nil && os.IsNotExist(err) {
+    doc.opts.Font = ""
+  }
+  for format, check := range exportChecks {
+    if format == "pdf" && doc.opts.Font == "" {
+      t.Log("skipping pdf, font not installed")
+      continue
+    }
+    content, err := exporters[format](doc)
+    if err != nil {
+      t.Errorf("%s: %v", format, err)
+      continue
+    }
+    check(t, doc, content)
+  }
+}
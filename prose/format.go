@@ -0,0 +1,110 @@
+package prose
+
+import (
+  "fmt"
+  "net/url"
+  "strconv"
+  "strings"
+)
+
+// On-disk .prose format. Version 1 files have no header; everything
+// from version 2 onward starts with "prose <version>".
+const (
+  formatMagic   = "prose"
+  formatVersion = 2
+)
+
+// migrations upgrade the raw lines of a file from version N to N+1.
+// A future format change bumps formatVersion and adds an entry here,
+// so the loader only ever parses the current version.
+var migrations = map[int]func([]string) []string{
+  1: migrateV1,
+}
+
+var escaper = strings.NewReplacer(
+  "%", "%25",
+  ",", "%2C",
+  " ", "%20",
+  "\t", "%09",
+  "\r", "%0D",
+  "\n", "%0A",
+)
+
+func escape(str string) string {
+  return escaper.Replace(str)
+}
+
+func unescape(str string) string {
+  if plain, err := url.PathUnescape(str); err == nil {
+    return plain
+  }
+  return str
+}
+
+// keyValue splits a variable or meta line into its name and value. An
+// empty value escapes to nothing, leaving only the name.
+func keyValue(line string) (string, string, bool) {
+  fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+  if len(fields) < 2 {
+    return "", "", false
+  }
+  val := ""
+  if len(fields) == 3 {
+    val = unescape(strings.TrimSpace(fields[2]))
+  }
+  return unescape(fields[1]), val, true
+}
+
+func formatHeader() string {
+  return fmt.Sprintf("%s %d", formatMagic, formatVersion)
+}
+
+// formatDetect returns the file version and the lines following the
+// header, if any.
+func formatDetect(lines []string) (int, []string) {
+  if len(lines) > 0 {
+    fields := strings.Fields(lines[0])
+    if len(fields) == 2 && fields[0] == formatMagic {
+      if version, err := strconv.Atoi(fields[1]); err == nil {
+        return version, lines[1:]
+      }
+    }
+  }
+  return 1, lines
+}
+
+func formatMigrate(version int, lines []string) ([]string, error) {
+  if version > formatVersion {
+    return nil, fmt.Errorf("format version %d is newer than %d", version, formatVersion)
+  }
+  for ; version < formatVersion; version++ {
+    migrate, ok := migrations[version]
+    if !ok {
+      return nil, fmt.Errorf("no migration from format version %d", version)
+    }
+    lines = migrate(lines)
+  }
+  return lines, nil
+}
+
+// migrateV1 escapes the unversioned format. Version 1 wrote variable
+// values and word text raw, so a value runs to the end of its line and
+// word text runs from the first comma.
+func migrateV1(lines []string) []string {
+  out := []string{}
+  for _, line := range lines {
+    if strings.HasPrefix(line, "variable") {
+      fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+      if len(fields) == 3 {
+        line = fmt.Sprintf("variable %s %s", escape(fields[1]), escape(fields[2]))
+      }
+    } else if !strings.HasPrefix(line, "paragraph") {
+      fields := strings.SplitN(line, ",", 2)
+      if len(fields) == 2 {
+        line = fmt.Sprintf("%s,%s", fields[0], escape(strings.TrimSpace(fields[1])))
+      }
+    }
+    out = append(out, line)
+  }
+  return out
+}
@@ -0,0 +1,240 @@
+package prose
+
+import (
+  "regexp"
+  "strings"
+)
+
+var (
+  fountainEscaper = strings.NewReplacer(
+    "\\", "\\\\",
+    "*", "\\*",
+    "_", "\\_",
+  )
+  fountainScene    = regexp.MustCompile(`(?i)^(INT|EXT|EST|INT\.?/EXT|I/E)[. ]`)
+  fountainNumber   = regexp.MustCompile(`\s*#[^#\s]+#$`)
+  fountainTitle    = regexp.MustCompile(`(?i)^(title|credit|author|authors|source|draft date|date|contact|copyright|notes)\s*:\s*(.*)$`)
+  fountainBoneyard = regexp.MustCompile(`(?s)/\*.*?\*/`)
+  fountainNote     = regexp.MustCompile(`(?s)\[\[.*?\]\]`)
+  // text Fountain would read as something other than action
+  fountainForce = regexp.MustCompile(`^([.!@~=#>]|\[\[|/\*)`)
+)
+
+// exportFountain writes a screenplay. Headings become scene headings,
+// paragraphs that open with an upper case cue followed only by quoted
+// words become dialogue, and everything else is action.
+func exportFountain(doc *DocAPI) ([]byte, error) {
+
+  out := []string{}
+  if title := doc.Meta("title"); title != "" {
+    out = append(out, "Title: "+title)
+  }
+  if author := doc.Meta("author"); author != "" {
+    out = append(out, "Author: "+author)
+  }
+  if len(out) > 0 {
+    out = append(out, "")
+  }
+
+  for _, para := range doc.Paragraphs() {
+    words := para.Words()
+
+    if para.style == Heading {
+      line := fountainInline(words)
+      if !fountainScene.MatchString(line) {
+        line = "." + line
+      }
+      out = append(out, line, "")
+      continue
+    }
+
+    if cue, ok := fountainDialogue(words); ok {
+      line := fountainInline(words[:cue])
+      if fountainForce.MatchString(line) || fountainScene.MatchString(line) {
+        line = "@" + line
+      }
+      out = append(out, line)
+
+      // dialogue is quoted in the editor, but not in the script
+      speech := []*WordAPI{}
+      for _, word := range words[cue:] {
+        plain := *word
+        plain.Clr(DQuote)
+        speech = append(speech, &plain)
+      }
+      for len(speech) > 0 {
+        n := 1
+        for n < len(speech) && speech[n].IsParen() == speech[0].IsParen() {
+          n++
+        }
+        out = append(out, fountainInline(speech[:n]))
+        speech = speech[n:]
+      }
+      out = append(out, "")
+      continue
+    }
+
+    line := fountainInline(words)
+    if fountainForce.MatchString(line) || fountainScene.MatchString(line) {
+      line = "!" + line
+    }
+    out = append(out, line, "")
+  }
+
+  return []byte(strings.Join(out, "\n")), nil
+}
+
+// fountainDialogue returns the number of cue words when the paragraph
+// reads as a character cue followed by quoted speech.
+func fountainDialogue(words []*WordAPI) (int, bool) {
+  cue := 0
+  for cue < len(words) && !words[cue].IsDQuote() {
+    cue++
+  }
+  if cue == 0 || cue == len(words) {
+    return 0, false
+  }
+  for _, word := range words[cue:] {
+    if !word.IsDQuote() {
+      return 0, false
+    }
+  }
+  name := ""
+  for _, word := range words[:cue] {
+    name += word.Display()
+  }
+  if strings.ToUpper(name) != name || strings.ToLower(name) == name {
+    return 0, false
+  }
+  return cue, true
+}
+
+func fountainInline(words []*WordAPI) string {
+  parts := []string{}
+  for i, word := range words {
+    prev, next := neighbours(words, i)
+    prefix, suffix, gap := word.Affixes(prev, next)
+
+    str := prefix + fountainEscaper.Replace(word.Display()) + suffix
+    if word.IsEmphasis() && (prev == nil || !prev.IsEmphasis()) {
+      str = "*" + str
+    }
+    if word.IsEmphasis() && (next == nil || !next.IsEmphasis()) {
+      str = str + "*"
+    }
+    parts = append(parts, str+gap)
+  }
+  return strings.TrimSpace(strings.Join(parts, ""))
+}
+
+func fountainCue(line string) bool {
+  if strings.HasPrefix(line, "@") {
+    return true
+  }
+  name := strings.TrimSpace(strings.TrimSuffix(line, "^"))
+  if i := strings.Index(name, "("); i > 0 {
+    name = name[:i]
+  }
+  return strings.ToUpper(name) == name && strings.ToLower(name) != name
+}
+
+// importFountain reads a screenplay. Scene headings become Heading,
+// action Content, and each dialogue block one paragraph holding the
+// character cue followed by the speech as DQuote words, parentheticals
+// as Paren. Title page entries fill in missing metadata; sections,
+// synopses, notes and the boneyard are dropped.
+func importFountain(doc *DocAPI, content []byte) (*imported, error) {
+
+  str := strings.Replace(string(content), "\r\n", "\n", -1)
+  str = fountainBoneyard.ReplaceAllString(str, "")
+  str = fountainNote.ReplaceAllString(str, "")
+  lines := strings.Split(str, "\n")
+
+  // title page
+  meta := map[string]string{}
+  if fountainTitle.MatchString(lines[0]) {
+    values := map[string]string{}
+    key := ""
+    for len(lines) > 0 && strings.TrimSpace(lines[0]) != "" {
+      line := lines[0]
+      lines = lines[1:]
+      if m := fountainTitle.FindStringSubmatch(line); m != nil {
+        key = strings.TrimSuffix(strings.ToLower(m[1]), "s")
+        line = m[2]
+      }
+      values[key] = strings.TrimSpace(values[key] + " " + strings.TrimSpace(line))
+    }
+    for _, key := range []string{"title", "author"} {
+      if doc.Meta(key) == "" && values[key] != "" {
+        meta[key] = values[key]
+      }
+    }
+  }
+
+  blocks := [][]string{}
+  block := []string{}
+  for _, line := range append(lines, "") {
+    trimmed := strings.TrimSpace(line)
+    switch {
+    case trimmed == "":
+      if len(block) > 0 {
+        blocks = append(blocks, block)
+      }
+      block = []string{}
+    case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "="):
+      // sections, synopses and page breaks
+    default:
+      block = append(block, trimmed)
+    }
+  }
+
+  paras := []*ParaAPI{}
+  add := func(style int, str string) {
+    para := newPara(doc)
+    para.style = style
+    para.Tokenize(str, true)
+    if !para.IsEmpty() {
+      paras = append(paras, para)
+    }
+  }
+
+  for _, block := range blocks {
+    first := block[0]
+
+    switch {
+    case strings.HasPrefix(first, "!"):
+      block[0] = first[1:]
+      add(Content, strings.Join(block, " "))
+
+    case strings.HasPrefix(first, ".") && !strings.HasPrefix(first, "..") || fountainScene.MatchString(first):
+      add(Heading, fountainNumber.ReplaceAllString(strings.TrimPrefix(first, "."), ""))
+      if len(block) > 1 {
+        add(Content, strings.Join(block[1:], " "))
+      }
+
+    case len(block) > 1 && fountainCue(first):
+      para := newPara(doc)
+      para.Tokenize(strings.TrimSuffix(strings.TrimPrefix(first, "@"), "^"), false)
+      cue := len(para.Words())
+      para.Tokenize(strings.Join(block[1:], " "), true)
+      for _, word := range para.Words()[cue:] {
+        word.Set(DQuote)
+      }
+      if !para.IsEmpty() {
+        paras = append(paras, para)
+      }
+
+    default:
+      for i, line := range block {
+        line = strings.TrimPrefix(line, "~")
+        if strings.HasPrefix(line, ">") {
+          line = strings.TrimSuffix(strings.TrimPrefix(line, ">"), "<")
+        }
+        block[i] = line
+      }
+      add(Content, strings.Join(block, " "))
+    }
+  }
+
+  return &imported{paras: paras, meta: meta}, nil
+}
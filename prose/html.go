@@ -0,0 +1,147 @@
+package prose
+
+import (
+  "fmt"
+  "html"
+  "image/color"
+  "path/filepath"
+  "strings"
+)
+
+// runs of words that share a span in HTML output, outermost first
+var htmlRuns = []struct {
+  flag uint64
+  open string
+  shut string
+}{
+  {DQuote, `<span class="quote">`, `</span>`},
+  {Paren, `<span class="paren">`, `</span>`},
+  {Emphasis, `<em>`, `</em>`},
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func cssColor(c color.RGBA) string {
+  return fmt.Sprintf("rgb(%d, %d, %d)", c.R, c.G, c.B)
+}
+
+// Title is the title metadata, or failing that the file's base name.
+func (self *DocAPI) Title() string {
+  if title := self.Meta("title"); title != "" {
+    return title
+  }
+  base := filepath.Base(self.path)
+  return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// exportHTML writes a single self-contained page coloured like the
+// editor itself.
+func exportHTML(doc *DocAPI) ([]byte, error) {
+
+  style := strings.Join([]string{
+    fmt.Sprintf("body { background: black; color: %s; font-family: sans-serif; font-size: 1.2em; line-height: 1.6; max-width: 40em; margin: 3em auto; padding: 0 1em; }", cssColor(FontColors[Content])),
+    fmt.Sprintf("h1 { color: %s; font-size: %.2fem; font-weight: normal; }", cssColor(FontColors[Heading]), FontSizes[Heading]/FontSizes[Content]),
+    fmt.Sprintf("ul { color: %s; }", cssColor(FontColors[Bullet])),
+    fmt.Sprintf(".quote { color: %s; }", cssColor(FontColors[Quote])),
+    fmt.Sprintf(".paren { color: %s; }", cssColor(FontColors[Comment])),
+    fmt.Sprintf("em { color: %s; }", cssColor(FontColors[Highlight])),
+  }, "\n")
+
+  out := []string{
+    "<!DOCTYPE html>",
+    "<html>",
+    "<head>",
+    `<meta charset="utf-8">`,
+    fmt.Sprintf("<title>%s</title>", html.EscapeString(doc.Title())),
+  }
+  if author := doc.Meta("author"); author != "" {
+    out = append(out, fmt.Sprintf(`<meta name="author" content="%s">`, html.EscapeString(author)))
+  }
+  out = append(out,
+    "<style>",
+    style,
+    "</style>",
+    "</head>",
+    "<body>",
+  )
+
+  out = append(out, htmlBlocks(doc.Paragraphs())...)
+  out = append(out, "</body>", "</html>", "")
+  return []byte(strings.Join(out, "\n")), nil
+}
+
+// htmlBlocks renders paragraphs as block elements, gathering runs of
+// bullets into lists. The output is also well-formed XHTML.
+func htmlBlocks(paras []*ParaAPI) []string {
+  out := []string{}
+  list := false
+  for _, para := range paras {
+    if list && para.style != Bullet {
+      out = append(out, "</ul>")
+      list = false
+    }
+    switch para.style {
+    case Heading:
+      out = append(out, "<h1>"+para.HTML()+"</h1>")
+    case Bullet:
+      if !list {
+        out = append(out, "<ul>")
+        list = true
+      }
+      out = append(out, "<li>"+para.HTML()+"</li>")
+    default:
+      out = append(out, "<p>"+para.HTML()+"</p>")
+    }
+  }
+  if list {
+    out = append(out, "</ul>")
+  }
+  return out
+}
+
+// HTML renders the paragraph's words as inline markup. Runs open as
+// late and close as early as possible while still nesting properly.
+func (self *ParaAPI) HTML() string {
+
+  words := self.Words()
+  parts := []string{}
+  stack := []int{}
+
+  sync := func(runs uint64) {
+    keep := 0
+    for keep < len(stack) && runs&htmlRuns[stack[keep]].flag != 0 {
+      keep++
+    }
+    for len(stack) > keep {
+      parts = append(parts, htmlRuns[stack[len(stack)-1]].shut)
+      stack = stack[:len(stack)-1]
+    }
+    for i, run := range htmlRuns {
+      open := false
+      for _, j := range stack {
+        open = open || i == j
+      }
+      if runs&run.flag != 0 && !open {
+        parts = append(parts, run.open)
+        stack = append(stack, i)
+      }
+    }
+  }
+
+  for i, word := range words {
+    prev, next := neighbours(words, i)
+    prefix, suffix, gap := word.Affixes(prev, next)
+
+    sync(word.flags)
+    parts = append(parts, htmlEscaper.Replace(prefix+word.Display()+suffix))
+
+    if next == nil {
+      sync(0)
+      break
+    }
+    sync(word.flags & next.flags)
+    parts = append(parts, gap)
+  }
+
+  return strings.Join(parts, "")
+}
@@ -0,0 +1,339 @@
+package prose
+
+import (
+  "archive/zip"
+  "bytes"
+  "encoding/xml"
+  "fmt"
+  "io/ioutil"
+  "path/filepath"
+  "regexp"
+  "sort"
+  "strings"
+)
+
+// imported is what an importer read from a file: its paragraphs, and
+// any metadata and variables the format carries. Importers only read the
+// document; Import applies the lot as one edit.
+type imported struct {
+  paras []*ParaAPI
+  meta  map[string]string
+  vars  map[string]string
+}
+
+// importers build paragraphs from a foreign file format, keyed by file
+// extension.
+var importers = map[string]func(*DocAPI, []byte) (*imported, error){
+  "txt":      importText,
+  "md":       importMarkdown,
+  "markdown": importMarkdown,
+  "docx":     importDOCX,
+  "odt":      importODT,
+  "fountain": importFountain,
+  "json":     importJSON,
+}
+
+// ImportFormats lists the file extensions Import understands, sorted.
+// Anything else is read as plain text.
+func ImportFormats() []string {
+  names := []string{}
+  for name, _ := range importers {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+func importFormat(path string) string {
+  ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+  if _, ok := importers[ext]; ok {
+    return ext
+  }
+  return "txt"
+}
+
+// Import reads a foreign file and inserts its paragraphs after the
+// current one, along with any metadata and variables it sets, as a
+// single undoable edit.
+func (self *DocAPI) Import(path string) error {
+
+  content, err := ioutil.ReadFile(path)
+  if err != nil {
+    return fmt.Errorf("import: %v", err)
+  }
+
+  in, err := importers[importFormat(path)](self, content)
+  if err != nil {
+    return fmt.Errorf("import %s: %v", path, err)
+  }
+
+  self.edit(nil, func() {
+    for key, val := range in.meta {
+      if val == "" {
+        delete(self.meta, key)
+        continue
+      }
+      self.meta[key] = val
+    }
+    for key, val := range in.vars {
+      self.vars[key] = val
+    }
+    self.splice(in.paras)
+  })
+  return nil
+}
+
+// Splice places whole paragraphs after the current one, leaving the
+// cursor at the end of the last.
+func (self *DocAPI) Splice(paras []*ParaAPI) {
+  if len(paras) == 0 {
+    return
+  }
+  self.edit(nil, func() {
+    self.splice(paras)
+  })
+}
+
+func (self *DocAPI) splice(paras []*ParaAPI) {
+  if len(paras) == 0 {
+    return
+  }
+  defer self.check()
+  self.Paragraph().Clean()
+  for _, para := range paras {
+    self.node = self.list.InsertAfter(para, self.node)
+    self.reordered()
+  }
+  self.Paragraph().Bottom()
+}
+
+var blankLines = regexp.MustCompile(`\n[ \t]*\n`)
+
+// importText treats blank lines as paragraph breaks. Text without any
+// blank lines is assumed to hold one paragraph per line.
+func importText(doc *DocAPI, content []byte) (*imported, error) {
+
+  str := strings.Replace(string(content), "\r\n", "\n", -1)
+
+  blocks := blankLines.Split(str, -1)
+  if len(blocks) == 1 {
+    blocks = strings.Split(str, "\n")
+  }
+
+  paras := []*ParaAPI{}
+  for _, block := range blocks {
+    para := newPara(doc)
+    para.Tokenize(block, false)
+    if !para.IsEmpty() {
+      paras = append(paras, para)
+    }
+  }
+  return &imported{paras: paras}, nil
+}
+
+// readZip loads every file of a package such as DOCX or ODT.
+func readZip(content []byte) (map[string][]byte, error) {
+  archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+  if err != nil {
+    return nil, err
+  }
+  files := map[string][]byte{}
+  for _, file := range archive.File {
+    r, err := file.Open()
+    if err != nil {
+      return nil, err
+    }
+    data, err := ioutil.ReadAll(r)
+    r.Close()
+    if err != nil {
+      return nil, fmt.Errorf("%s: %v", file.Name, err)
+    }
+    files[file.Name] = data
+  }
+  return files, nil
+}
+
+func xmlAttr(e xml.StartElement, local string) string {
+  for _, attr := range e.Attr {
+    if attr.Name.Local == local {
+      return attr.Value
+    }
+  }
+  return ""
+}
+
+// emphasisMark brackets emphasised text handed to Tokenize by the rich
+// format importers. It is a private use character so never clashes.
+const emphasisMark = "\uE000"
+
+// richText gathers one paragraph of a styled document as runs of text.
+type richText struct {
+  style    int
+  parts    []string
+  run      string
+  emphasis bool
+}
+
+func (self *richText) Add(str string, emphasis bool) {
+  if emphasis != self.emphasis {
+    self.flush()
+    self.emphasis = emphasis
+  }
+  self.run += str
+}
+
+// flush keeps the marks next to the words so surrounding spaces do not
+// carry emphasis onto neighbours.
+func (self *richText) flush() {
+  run := self.run
+  self.run = ""
+  if core := strings.TrimSpace(run); self.emphasis && core != "" {
+    i := strings.Index(run, core)
+    run = run[:i] + emphasisMark + core + emphasisMark + run[i+len(core):]
+  }
+  self.parts = append(self.parts, run)
+}
+
+func (self *richText) Para(doc *DocAPI) *ParaAPI {
+  self.flush()
+  para := newPara(doc)
+  para.style = self.style
+  para.Tokenize(strings.Join(self.parts, ""), false)
+  return para
+}
+
+var (
+  openQuotes  = []string{"\"", "“", "„"}
+  closeQuotes = []string{"\"", "”"}
+  punctuation = []struct {
+    mark string
+    flag uint64
+  }{
+    {"...", Ellipsis},
+    {"…", Ellipsis},
+    {",", Comma},
+    {".", Period},
+    {"!", Exclaim},
+    {"?", Question},
+    {":", Colon},
+    {";", SemiColon},
+  }
+  markdownUnescape = regexp.MustCompile(`\\([[:punct:]])`)
+)
+
+func trimAny(str string, marks []string, trim func(string, string) string) (string, bool) {
+  for _, mark := range marks {
+    if s := trim(str, mark); len(s) < len(str) {
+      return s, true
+    }
+  }
+  return str, false
+}
+
+// hasText reports whether anything but emphasis markers is left of a
+// token, so a lone or doubled * or _ is not taken for one.
+func hasText(token string) bool {
+  return strings.Trim(token, "*_"+emphasisMark) != ""
+}
+
+// Tokenize appends words parsed from plain text, turning punctuation,
+// quotes and parentheses into word flags. Text between emphasisMarks is
+// Emphasis. With markdown set, *runs* and _runs_ are too and backslash
+// escapes are honoured.
+func (self *ParaAPI) Tokenize(str string, markdown bool) {
+  defer self.check()
+
+  self.Bottom()
+
+  emphMarks := []string{emphasisMark}
+  if markdown {
+    emphMarks = append(emphMarks, "**", "__", "*", "_")
+  }
+
+  flags := uint64(0)
+
+  for _, token := range strings.Fields(str) {
+
+    open := uint64(0)
+    for more := true; more; {
+      more = false
+      var ok bool
+      if token, ok = trimAny(token, openQuotes, strings.TrimPrefix); ok {
+        open |= DQuote
+        more = true
+      }
+      if token, ok = trimAny(token, []string{"("}, strings.TrimPrefix); ok {
+        open |= Paren
+        more = true
+      }
+      // a marker standing alone is text, not the start of a run
+      if rest, ok := trimAny(token, emphMarks, strings.TrimPrefix); ok && hasText(rest) {
+        token = rest
+        open |= Emphasis
+        more = true
+      }
+    }
+
+    shut := uint64(0)
+    punct := uint64(0)
+    for more := true; more; {
+      more = false
+      var ok bool
+      if token, ok = trimAny(token, closeQuotes, strings.TrimSuffix); ok {
+        shut |= DQuote
+        more = true
+      }
+      if token, ok = trimAny(token, []string{")"}, strings.TrimSuffix); ok {
+        shut |= Paren
+        more = true
+      }
+      // a backslash-escaped marker is part of the text
+      if !strings.HasSuffix(strings.TrimRight(token, "*_"), "\\") {
+        if rest, ok := trimAny(token, emphMarks, strings.TrimSuffix); ok && hasText(rest) {
+          token = rest
+          shut |= Emphasis
+          more = true
+        }
+      }
+      for _, p := range punctuation {
+        if len(token) > len(p.mark) && strings.HasSuffix(token, p.mark) {
+          token = strings.TrimSuffix(token, p.mark)
+          // the mark nearest the word wins
+          punct = p.flag
+          more = true
+          break
+        }
+      }
+    }
+
+    // emphasis starting or ending inside a word is dropped
+    token = strings.Replace(token, emphasisMark, "", -1)
+
+    if markdown {
+      token = markdownUnescape.ReplaceAllString(token, "$1")
+    }
+
+    flags |= open
+
+    parts := []string{token}
+    if !strings.HasPrefix(token, "-") && !strings.HasSuffix(token, "-") && !strings.Contains(token, "--") {
+      parts = strings.Split(token, "-")
+    }
+
+    for i, part := range parts {
+      word := newWord(self)
+      word.text = part
+      word.flags = flags
+      if i < len(parts)-1 {
+        word.Set(Hyphen)
+      } else {
+        word.Set(punct)
+      }
+      if !word.IsEmpty() {
+        self.AddWord(word)
+      }
+    }
+
+    flags &^= shut
+  }
+}
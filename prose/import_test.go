@@ -0,0 +1,260 @@
+package prose
+
+import (
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+// tokens shows what Tokenize made of a line, wrapping emphasised words in
+// slashes and ending words with their punctuation.
+func tokens(str string, markdown bool) string {
+  para := newPara(nil)
+  para.Tokenize(str, markdown)
+  words := []string{}
+  for _, word := range para.Words() {
+    text := word.Text()
+    if word.Is(Emphasis) {
+      text = "/" + text + "/"
+    }
+    if word.Is(Period) {
+      text += "."
+    }
+    if word.Is(Comma) {
+      text += ","
+    }
+    words = append(words, text)
+  }
+  return strings.Join(words, " ")
+}
+
+func TestTokenize(t *testing.T) {
+  tests := []struct {
+    line     string
+    markdown bool
+    want     string
+  }{
+    {"plain words.", false, "plain words."},
+    {"*not* markdown", false, "*not* markdown"},
+    {"an *emphasised* word", true, "an /emphasised/ word"},
+    {"a **strong** word", true, "a /strong/ word"},
+    {"_two words_ then", true, "/two/ /words/ then"},
+    {"*so*, then", true, "/so/, then"},
+    {"one * two", true, "one * two"},
+    {"one _ two", true, "one _ two"},
+    {"one ** two", true, "one ** two"},
+    {"***", true, "***"},
+    {"a * b *c* d", true, "a * b /c/ d"},
+    {"*a * b*", true, "/a/ /*/ /b/"},
+    {`\*literal\*`, true, "*literal*"},
+  }
+  for _, test := range tests {
+    if got := tokens(test.line, test.markdown); got != test.want {
+      t.Errorf("Tokenize(%q, %v) = %q, want %q", test.line, test.markdown, got, test.want)
+    }
+  }
+}
+
+// marked shows a word with each of its flags, quotes and parentheses
+// around the word alone and emphasis as _word_.
+func marked(word *WordAPI) string {
+  str := word.Display()
+  if word.Is(Emphasis) {
+    str = "_" + str + "_"
+  }
+  for _, p := range punctuation {
+    if word.Is(p.flag) {
+      str += p.mark
+      break
+    }
+  }
+  if word.Is(Hyphen) {
+    str += "-"
+  }
+  if word.Is(DQuote) {
+    str = `"` + str + `"`
+  }
+  if word.Is(Paren) {
+    str = "(" + str + ")"
+  }
+  return str
+}
+
+// describe shows imported paragraphs one per line, by style.
+func describe(paras []*ParaAPI) string {
+  out := []string{}
+  for _, para := range paras {
+    words := []string{}
+    for _, word := range para.Words() {
+      words = append(words, marked(word))
+    }
+    out = append(out, styleNames[para.Style()]+": "+strings.Join(words, " "))
+  }
+  return strings.Join(out, "\n")
+}
+
+func zipped(t *testing.T, files ...string) []byte {
+  entries := []zipEntry{}
+  for i := 0; i+1 < len(files); i += 2 {
+    entries = append(entries, zipEntry{name: files[i], body: files[i+1]})
+  }
+  content, err := writeZip(entries)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return content
+}
+
+func TestImporters(t *testing.T) {
+  tests := []struct {
+    format  string
+    content []byte
+    want    string
+  }{
+    {"txt", []byte("One line.\nAnother \"quoted\" line."), strings.Join([]string{
+      "content: One line.",
+      `content: Another "quoted" line.`,
+    }, "\n")},
+
+    {"md", []byte(strings.Join([]string{
+      "# Title #",
+      "",
+      "Some *emphasised* text",
+      "run on, with \\*stars\\*.",
+      "",
+      "- one",
+      "- two",
+      "",
+      "> quoted",
+      "",
+      "***",
+      "```",
+      "code",
+      "```",
+    }, "\n")), strings.Join([]string{
+      "heading: Title",
+      "content: Some _emphasised_ text run on, with *stars*.",
+      "bullet: one",
+      "bullet: two",
+      "content: quoted",
+      "content: code",
+    }, "\n")},
+
+    {"docx", zipped(t,
+      "word/styles.xml", `<w:styles xmlns:w="`+docxMain+`">
+        <w:style w:styleId="Heading1"><w:name w:val="heading 1"/></w:style>
+        <w:style w:styleId="Strong"><w:name w:val="Strong"/><w:rPr><w:b/></w:rPr></w:style>
+      </w:styles>`,
+      "word/document.xml", `<w:document xmlns:w="`+docxMain+`"><w:body>
+        <w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Chapter</w:t></w:r></w:p>
+        <w:p><w:r><w:t xml:space="preserve">Plain and </w:t></w:r><w:r><w:rPr><w:i/></w:rPr><w:t>italic</w:t></w:r><w:r><w:t xml:space="preserve"> then </w:t></w:r><w:r><w:rPr><w:rStyle w:val="Strong"/></w:rPr><w:t>strong.</w:t></w:r></w:p>
+        <w:p><w:pPr><w:numPr><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>listed</w:t></w:r></w:p>
+        <w:p><w:r><w:t>not </w:t></w:r><w:r><w:rPr><w:i w:val="0"/></w:rPr><w:t>italic</w:t></w:r></w:p>
+      </w:body></w:document>`,
+    ), strings.Join([]string{
+      "heading: Chapter",
+      "content: Plain and _italic_ then _strong_.",
+      "bullet: listed",
+      "content: not italic",
+    }, "\n")},
+
+    {"odt", zipped(t,
+      "styles.xml", `<office:document-styles xmlns:office="`+odtOffice+`" xmlns:style="`+odtStyle+`"><office:styles>
+        <style:style style:name="Title" style:display-name="Title"/>
+      </office:styles></office:document-styles>`,
+      "content.xml", `<office:document-content xmlns:office="`+odtOffice+`" xmlns:style="`+odtStyle+`" xmlns:text="`+odtText+`">
+        <office:automatic-styles>
+          <style:style style:name="T1"><style:text-properties fo:font-style="italic" xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"/></style:style>
+        </office:automatic-styles>
+        <office:body><office:text>
+          <text:h>Part<text:note><text:p>a footnote</text:p></text:note></text:h>
+          <text:p text:style-name="Title">Named</text:p>
+          <text:p>Some <text:span text:style-name="T1">slanted</text:span> words<text:s text:c="3"/>spaced.</text:p>
+          <text:list><text:list-item><text:p>item</text:p></text:list-item></text:list>
+          <text:p>noted<office:annotation><text:p>a comment</text:p></office:annotation></text:p>
+        </office:text></office:body>
+      </office:document-content>`,
+    ), strings.Join([]string{
+      "heading: Part",
+      "heading: Named",
+      "content: Some _slanted_ words spaced.",
+      "bullet: item",
+      "content: noted",
+    }, "\n")},
+
+    {"fountain", []byte(strings.Join([]string{
+      "Title: The Script",
+      "Author: Someone",
+      "",
+      "INT. HOUSE - DAY #1#",
+      "",
+      "A room. [[a note]]",
+      "",
+      "BOB",
+      "(quietly)",
+      "Hello there.",
+      "",
+      ".FLASHBACK",
+      "",
+      "/* cut */",
+      "!SHOUTED ACTION",
+      "",
+      "# Act Two",
+      "",
+      "> THE END <",
+    }, "\n")), strings.Join([]string{
+      "heading: INT. HOUSE - DAY",
+      "content: A room.",
+      `content: BOB ("quietly") "Hello" "there."`,
+      "heading: FLASHBACK",
+      "content: SHOUTED ACTION",
+      "content: THE END",
+    }, "\n")},
+  }
+
+  for _, test := range tests {
+    doc := Create("", DefaultOptions())
+    in, err := importers[test.format](doc, test.content)
+    if err != nil {
+      t.Errorf("%s: %v", test.format, err)
+      continue
+    }
+    if got := describe(in.paras); got != test.want {
+      t.Errorf("%s imported\n%s\nwant\n%s", test.format, got, test.want)
+    }
+    if test.format == "fountain" && in.meta["title"] != "The Script" {
+      t.Errorf("fountain title %q", in.meta["title"])
+    }
+    if len(doc.Paragraphs()) > 0 || len(doc.meta) > 0 {
+      t.Errorf("%s importer changed the document", test.format)
+    }
+  }
+}
+
+// A title page comes in with the screenplay, as one undo step.
+func TestFountainImportUndo(t *testing.T) {
+  dir := t.TempDir()
+  path := filepath.Join(dir, "script.fountain")
+  script := "Title: The Script\nAuthor: Someone\n\nINT. HOUSE - DAY\n\nA room."
+  if err := ioutil.WriteFile(path, []byte(script), 0644); err != nil {
+    t.Fatal(err)
+  }
+
+  doc := Create(filepath.Join(dir, "script.prose"), DefaultOptions())
+  doc.SetMeta("author", "Kept")
+  if err := doc.Import(path); err != nil {
+    t.Fatal(err)
+  }
+  if doc.Meta("title") != "The Script" || doc.Meta("author") != "Kept" {
+    t.Errorf("imported title %q, author %q", doc.Meta("title"), doc.Meta("author"))
+  }
+
+  doc.Undo()
+  if len(doc.Paragraphs()) > 0 || doc.Meta("title") != "" {
+    t.Errorf("one undo left %d paragraphs and title %q", len(doc.Paragraphs()), doc.Meta("title"))
+  }
+  if doc.Meta("author") != "Kept" {
+    t.Errorf("undoing the import dropped the author set before it")
+  }
+}
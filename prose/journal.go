@@ -0,0 +1,344 @@
+package prose
+
+import (
+  "bufio"
+  "crypto/sha1"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "strconv"
+  "strings"
+)
+
+// The journal is an append-only log of edits made since the last save,
+// kept beside the document so a crash loses nothing typed in between.
+//
+//   journal <sha1 of the saved file>
+//   base <id> <id> ...            paragraph ids in saved file order
+//   order <id> <id> ...           paragraph order changed
+//   para <id> <style> <focus> <word> <word> ...
+//   focus <id>
+//   variable <key> <value>
+//   drop <key>
+//   meta <key> <value>
+//   dropmeta <key>
+//
+// Paragraph ids are only meaningful within one journal; base maps them
+// onto the paragraphs of the saved file by position.
+type journalAPI struct {
+  file  *os.File
+  order []uint64
+  paras []*ParaAPI // the order last written, to skip comparing ids
+  focus uint64
+  err   error
+}
+
+func journalPath(path string) string {
+  return path + ".journal"
+}
+
+func journalHash(content []byte) string {
+  return fmt.Sprintf("%x", sha1.Sum(content))
+}
+
+func idList(paras []*ParaAPI) []uint64 {
+  ids := []uint64{}
+  for _, para := range paras {
+    ids = append(ids, para.id)
+  }
+  return ids
+}
+
+func idLine(prefix string, ids []uint64) string {
+  fields := []string{prefix}
+  for _, id := range ids {
+    fields = append(fields, strconv.FormatUint(id, 10))
+  }
+  return strings.Join(fields, " ")
+}
+
+// newJournal starts a fresh journal for a document whose saved content
+// hashes to hash and whose paragraphs, in file order, are base.
+func newJournal(path string, hash string, base []*ParaAPI) (*journalAPI, error) {
+  self := &journalAPI{}
+  self.order = idList(base)
+
+  header := fmt.Sprintf("journal %s\n%s\n", hash, idLine("base", self.order))
+  if err := writeAtomic(journalPath(path), []byte(header)); err != nil {
+    return nil, err
+  }
+
+  file, err := os.OpenFile(journalPath(path), os.O_WRONLY|os.O_APPEND, 0644)
+  if err != nil {
+    return nil, err
+  }
+  self.file = file
+  return self, nil
+}
+
+func (self *journalAPI) close() {
+  if self != nil && self.file != nil {
+    self.file.Close()
+    self.file = nil
+  }
+}
+
+// write appends whatever changed between two document states.
+func (self *journalAPI) write(before *docState, after *docState) {
+
+  if self == nil || self.file == nil {
+    return
+  }
+
+  lines := []string{}
+
+  // the order, and with it any new paragraphs, can only have changed if
+  // the state holds a different order slice
+  moved := !sameOrder(after.paras, self.paras)
+  known := map[uint64]bool{}
+
+  if moved {
+    self.paras = after.paras
+    for _, id := range self.order {
+      known[id] = true
+    }
+    order := idList(after.paras)
+    if !sameIds(order, self.order) {
+      lines = append(lines, idLine("order", order))
+      self.order = order
+    }
+  }
+
+  prev := map[*ParaAPI]paraState{}
+  for _, ps := range before.saved {
+    prev[ps.para] = ps
+  }
+  for _, ps := range after.saved {
+    if old, ok := prev[ps.para]; !ok || !old.equals(ps) {
+      lines = append(lines, paraLine(ps))
+    }
+    known[ps.para.id] = true
+  }
+
+  // paragraphs spliced in beyond the cursor's neighbours
+  if moved {
+    for _, para := range after.paras {
+      if !known[para.id] {
+        lines = append(lines, paraLine(para.state()))
+      }
+    }
+  }
+
+  lines = append(lines, mapLines("variable", "drop", before.vars, after.vars)...)
+  lines = append(lines, mapLines("meta", "dropmeta", before.meta, after.meta)...)
+
+  if after.focus.id != self.focus {
+    lines = append(lines, fmt.Sprintf("focus %d", after.focus.id))
+    self.focus = after.focus.id
+  }
+
+  if len(lines) == 0 {
+    return
+  }
+
+  _, err := self.file.WriteString(strings.Join(lines, "\n") + "\n")
+  if err == nil {
+    err = self.file.Sync()
+  }
+  if err != nil {
+    self.err = fmt.Errorf("journal: %v", err)
+    self.close()
+  }
+}
+
+// mapLines records the changes between two versions of the variables or
+// metadata.
+func mapLines(set string, drop string, before map[string]string, after map[string]string) []string {
+  lines := []string{}
+  for _, key := range sortedKeys(after) {
+    if val, ok := before[key]; !ok || val != after[key] {
+      lines = append(lines, fmt.Sprintf("%s %s %s", set, escape(key), escape(after[key])))
+    }
+  }
+  for _, key := range sortedKeys(before) {
+    if _, ok := after[key]; !ok {
+      lines = append(lines, fmt.Sprintf("%s %s", drop, escape(key)))
+    }
+  }
+  return lines
+}
+
+func paraLine(ps paraState) string {
+  fields := []string{"para",
+    strconv.FormatUint(ps.para.id, 10),
+    strconv.Itoa(ps.style),
+    strconv.Itoa(ps.focus),
+  }
+  for _, ws := range ps.words {
+    fields = append(fields, fmt.Sprintf("%d,%s", ws.flags, escape(ws.text)))
+  }
+  return strings.Join(fields, " ")
+}
+
+func sameIds(a []uint64, b []uint64) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for i := range a {
+    if a[i] != b[i] {
+      return false
+    }
+  }
+  return true
+}
+
+// resume recovers any journal left by a crash, then starts a new one
+// against the file as loaded.
+func (self *DocAPI) resume(hash string, base []*ParaAPI) {
+
+  if self.replay(hash, base) {
+    note(self.path, "recovered unsaved edits from", journalPath(self.path))
+    self.check()
+    self.edited = true
+    // the old journal stays on disk until a save succeeds
+    if err := self.Save(); err != nil {
+      note(err)
+    }
+    return
+  }
+
+  journal, err := newJournal(self.path, hash, base)
+  if err != nil {
+    note(self.path, err)
+    return
+  }
+  self.journal = journal
+}
+
+// replay applies a journal left behind by a crash. It reports whether
+// anything was recovered.
+func (self *DocAPI) replay(hash string, base []*ParaAPI) bool {
+
+  path := journalPath(self.path)
+
+  jinfo, err := os.Stat(path)
+  if err != nil {
+    return false
+  }
+  if dinfo, err := os.Stat(self.path); err == nil && !jinfo.ModTime().After(dinfo.ModTime()) {
+    return false
+  }
+
+  content, err := ioutil.ReadFile(path)
+  if err != nil {
+    note(path, err)
+    return false
+  }
+
+  // ignore a final line cut short by the crash
+  if i := strings.LastIndex(string(content), "\n"); i >= 0 {
+    content = content[:i+1]
+  }
+
+  scanner := bufio.NewScanner(strings.NewReader(string(content)))
+  scanner.Buffer(nil, len(content)+1)
+
+  if !scanner.Scan() || scanner.Text() != "journal "+hash {
+    return false
+  }
+  if !scanner.Scan() {
+    return false
+  }
+
+  paras := map[uint64]*ParaAPI{}
+  order := []uint64{}
+
+  fields := strings.Fields(scanner.Text())
+  if len(fields) != len(base)+1 || fields[0] != "base" {
+    note(path, "does not match", self.path)
+    return false
+  }
+  for i, field := range fields[1:] {
+    id, _ := strconv.ParseUint(field, 10, 64)
+    paras[id] = base[i]
+    order = append(order, id)
+  }
+
+  lookup := func(field string) (uint64, *ParaAPI) {
+    id, _ := strconv.ParseUint(field, 10, 64)
+    if _, ok := paras[id]; !ok {
+      paras[id] = newPara(self)
+    }
+    return id, paras[id]
+  }
+
+  focus := (*ParaAPI)(nil)
+  replayed := false
+
+  for scanner.Scan() {
+    fields := strings.Fields(scanner.Text())
+    if len(fields) == 0 {
+      continue
+    }
+    switch {
+
+    case fields[0] == "order":
+      order = []uint64{}
+      for _, field := range fields[1:] {
+        id, _ := lookup(field)
+        order = append(order, id)
+      }
+
+    case fields[0] == "para" && len(fields) >= 4:
+      _, para := lookup(fields[1])
+      state := paraState{para: para, words: []wordState{}}
+      state.style, _ = strconv.Atoi(fields[2])
+      state.focus, _ = strconv.Atoi(fields[3])
+      for _, field := range fields[4:] {
+        word := newWord(para)
+        word.Import(field)
+        state.words = append(state.words, wordState{word.text, word.flags})
+      }
+      para.restore(state)
+
+    case fields[0] == "focus" && len(fields) == 2:
+      _, focus = lookup(fields[1])
+
+    case fields[0] == "variable" && (len(fields) == 2 || len(fields) == 3):
+      key, val, _ := keyValue(scanner.Text())
+      self.vars[key] = val
+
+    case fields[0] == "drop" && len(fields) == 2:
+      delete(self.vars, unescape(fields[1]))
+
+    case fields[0] == "meta" && (len(fields) == 2 || len(fields) == 3):
+      key, val, _ := keyValue(scanner.Text())
+      self.meta[key] = val
+
+    case fields[0] == "dropmeta" && len(fields) == 2:
+      delete(self.meta, unescape(fields[1]))
+
+    default:
+      continue
+    }
+    replayed = true
+  }
+
+  if !replayed {
+    return false
+  }
+
+  self.list.Init()
+  self.reordered()
+  self.node = nil
+  for _, id := range order {
+    e := self.list.PushBack(paras[id])
+    if paras[id] == focus {
+      self.node = e
+    }
+  }
+  if self.node == nil {
+    self.node = self.list.Back()
+  }
+  return true
+}
@@ -0,0 +1,91 @@
+package prose
+
+import (
+  "fmt"
+  "os"
+  "path/filepath"
+  "testing"
+)
+
+// edited saves a document, then edits it in every way the journal
+// records without saving again.
+func edited(t *testing.T) (*DocAPI, string) {
+  path := filepath.Join(t.TempDir(), "novel.prose")
+  doc, err := NewDoc(path, DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+  typeParas(doc, "saved first", "saved second")
+  doc.Set("kept", "100%, really")
+  doc.Set("dropped", "soon")
+  doc.SetMeta("author", "Someone")
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+
+  doc.Space()
+  doc.Insert("unsaved")
+  doc.Comma()
+  doc.Space()
+  doc.Return()
+  doc.Insert("new")
+  doc.Heading()
+  doc.ShiftUp()
+  doc.Set("added", "a b")
+  doc.Drop("dropped")
+  doc.SetMeta("title", "Draft, 100%")
+  doc.SetMeta("author", "")
+  return doc, path
+}
+
+func TestJournalReplay(t *testing.T) {
+  doc, path := edited(t)
+  want := lines(doc)
+
+  back := crash(t, path)
+  if got := lines(back); got != want {
+    t.Errorf("recovered\n%s\nwant\n%s", got, want)
+  }
+  if _, err := os.Stat(journalPath(path)); err != nil {
+    t.Errorf("no fresh journal after recovery: %v", err)
+  }
+
+  // recovery saved the document, so opening it again changes nothing
+  if got := lines(crash(t, path)); got != want {
+    t.Errorf("reopened\n%s\nwant\n%s", got, want)
+  }
+}
+
+func TestJournalTornLine(t *testing.T) {
+  doc, path := edited(t)
+  want := lines(doc)
+
+  file, err := os.OpenFile(journalPath(path), os.O_WRONLY|os.O_APPEND, 0644)
+  if err != nil {
+    t.Fatal(err)
+  }
+  // a line the crash cut short, for a paragraph the journal knows
+  fmt.Fprintf(file, "para %d %d 0 0,cut", doc.Paragraph().id, Content)
+  file.Close()
+
+  if got := lines(crash(t, path)); got != want {
+    t.Errorf("recovered\n%s\nwant\n%s", got, want)
+  }
+}
+
+func TestJournalAfterSave(t *testing.T) {
+  doc, path := edited(t)
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+  want := lines(doc)
+
+  // a journal no newer than the file has nothing to add
+  back, err := NewDoc(path, DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := lines(back); got != want {
+    t.Errorf("reopened\n%s\nwant\n%s", got, want)
+  }
+}
@@ -0,0 +1,132 @@
+package prose
+
+import (
+  "encoding/json"
+  "fmt"
+)
+
+// names for word flags in JSON, in bit order
+var flagNames = []string{
+  "dquote",
+  "period",
+  "exclaim",
+  "question",
+  "comma",
+  "ellipsis",
+  "hyphen",
+  "paren",
+  "colon",
+  "semicolon",
+  "emphasis",
+  "variable",
+}
+
+var styleNames = map[int]string{
+  Heading: "heading",
+  Content: "content",
+  Bullet:  "bullet",
+}
+
+type jsonWord struct {
+  Text  string   `json:"text"`
+  Flags []string `json:"flags,omitempty"`
+}
+
+type jsonPara struct {
+  Style string     `json:"style"`
+  Words []jsonWord `json:"words"`
+}
+
+type jsonDoc struct {
+  Format     string            `json:"format"`
+  Version    int               `json:"version"`
+  Meta       map[string]string `json:"meta"`
+  Variables  map[string]string `json:"variables"`
+  Paragraphs []jsonPara        `json:"paragraphs"`
+}
+
+// exportJSON writes everything Save would, with flags and styles named
+// so other tools need not know the bitmask.
+func exportJSON(doc *DocAPI) ([]byte, error) {
+
+  out := jsonDoc{
+    Format:     formatMagic,
+    Version:    formatVersion,
+    Meta:       doc.meta,
+    Variables:  doc.vars,
+    Paragraphs: []jsonPara{},
+  }
+
+  for e := doc.list.Front(); e != nil; e = e.Next() {
+    para := e.Value.(*ParaAPI)
+    jp := jsonPara{Style: styleNames[para.style], Words: []jsonWord{}}
+    for _, word := range para.Words() {
+      jw := jsonWord{Text: word.text}
+      for bit, name := range flagNames {
+        if word.Is(1 << uint(bit)) {
+          jw.Flags = append(jw.Flags, name)
+        }
+      }
+      jp.Words = append(jp.Words, jw)
+    }
+    out.Paragraphs = append(out.Paragraphs, jp)
+  }
+
+  content, err := json.MarshalIndent(out, "", "  ")
+  if err != nil {
+    return nil, err
+  }
+  return append(content, '\n'), nil
+}
+
+// importJSON reads the output of exportJSON. Metadata and variables in
+// the file replace those of the same name in the document.
+func importJSON(doc *DocAPI, content []byte) (*imported, error) {
+
+  in := jsonDoc{}
+  if err := json.Unmarshal(content, &in); err != nil {
+    return nil, err
+  }
+  if in.Format != formatMagic {
+    return nil, fmt.Errorf("not a %s document", formatMagic)
+  }
+  if in.Version > formatVersion {
+    return nil, fmt.Errorf("document version %d is newer than %d", in.Version, formatVersion)
+  }
+
+  flags := map[string]uint64{}
+  for bit, name := range flagNames {
+    flags[name] = 1 << uint(bit)
+  }
+  styles := map[string]int{}
+  for style, name := range styleNames {
+    styles[name] = style
+  }
+
+  paras := []*ParaAPI{}
+  for i, jp := range in.Paragraphs {
+    para := newPara(doc)
+    style, ok := styles[jp.Style]
+    if !ok {
+      return nil, fmt.Errorf("paragraph %d: unknown style %q", i+1, jp.Style)
+    }
+    para.style = style
+    for _, jw := range jp.Words {
+      word := newWord(para)
+      word.text = jw.Text
+      for _, name := range jw.Flags {
+        flag, ok := flags[name]
+        if !ok {
+          return nil, fmt.Errorf("paragraph %d: unknown flag %q", i+1, name)
+        }
+        word.Set(flag)
+      }
+      if !word.IsEmpty() {
+        para.AddWord(word)
+      }
+    }
+    paras = append(paras, para)
+  }
+
+  return &imported{paras: paras, meta: in.Meta, vars: in.Variables}, nil
+}
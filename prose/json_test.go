@@ -0,0 +1,83 @@
+package prose
+
+import (
+  "io/ioutil"
+  "path/filepath"
+  "testing"
+)
+
+// A JSON export imported into an empty document exports again byte for
+// byte.
+func TestJSONRoundTrip(t *testing.T) {
+  first, err := exportJSON(sample(t))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  dir := t.TempDir()
+  path := filepath.Join(dir, "sample.json")
+  if err := ioutil.WriteFile(path, first, 0644); err != nil {
+    t.Fatal(err)
+  }
+  doc := Create(filepath.Join(dir, "copy.prose"), DefaultOptions())
+  if err := doc.Import(path); err != nil {
+    t.Fatal(err)
+  }
+
+  second, err := exportJSON(doc)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if string(first) != string(second) {
+    t.Errorf("exported\n%s\nwant\n%s", second, first)
+  }
+}
+
+func TestJSONImportErrors(t *testing.T) {
+  for _, content := range []string{
+    `not json`,
+    `{"format": "other", "version": 2}`,
+    `{"format": "prose", "version": 99}`,
+    `{"format": "prose", "version": 2, "paragraphs": [{"style": "poem", "words": []}]}`,
+    `{"format": "prose", "version": 2, "paragraphs": [{"style": "content", "words": [{"text": "x", "flags": ["bold"]}]}]}`,
+  } {
+    doc := Create("", DefaultOptions())
+    if _, err := importJSON(doc, []byte(content)); err == nil {
+      t.Errorf("imported %s", content)
+    }
+  }
+}
+
+// Import brings in paragraphs, metadata and variables as one undo step.
+func TestJSONImportUndo(t *testing.T) {
+  content, err := exportJSON(sample(t))
+  if err != nil {
+    t.Fatal(err)
+  }
+  dir := t.TempDir()
+  path := filepath.Join(dir, "sample.json")
+  if err := ioutil.WriteFile(path, content, 0644); err != nil {
+    t.Fatal(err)
+  }
+
+  doc := Create(filepath.Join(dir, "copy.prose"), DefaultOptions())
+  empty := lines(doc)
+  if err := doc.Import(path); err != nil {
+    t.Fatal(err)
+  }
+  full := lines(doc)
+
+  if !doc.Undo() {
+    t.Fatal("import left nothing to undo")
+  }
+  if got := lines(doc); got != empty {
+    t.Errorf("one undo left\n%s", got)
+  }
+  if doc.Undo() {
+    t.Error("import took more than one undo step")
+  }
+  doc.Redo()
+  if got := lines(doc); got != full {
+    t.Errorf("redo gave\n%s\nwant\n%s", got, full)
+  }
+}
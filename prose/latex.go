@@ -0,0 +1,138 @@
+package prose
+
+import (
+  "fmt"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+)
+
+var latexEscaper = strings.NewReplacer(
+  `\`, `\textbackslash{}`,
+  `{`, `\{`,
+  `}`, `\}`,
+  `$`, `\$`,
+  `&`, `\&`,
+  `#`, `\#`,
+  `%`, `\%`,
+  `_`, `\_`,
+  `^`, `\textasciicircum{}`,
+  `~`, `\textasciitilde{}`,
+  `"`, `''`,
+  `…`, `\ldots{}`,
+)
+
+// classes with a \chapter above \section
+var latexChapters = map[string]bool{
+  "book":     true,
+  "report":   true,
+  "memoir":   true,
+  "scrbook":  true,
+  "scrreprt": true,
+}
+
+const latexPreamble = `\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+`
+
+// exportLaTeX writes a complete .tex file. "meta class book" picks the
+// document class and "meta preamble style.tex" replaces the default
+// preamble with a file, relative to the document.
+func exportLaTeX(doc *DocAPI) ([]byte, error) {
+
+  class := doc.Meta("class")
+  if class == "" {
+    class = "article"
+  }
+  options := ""
+  if paper := strings.ToLower(doc.Meta("paper")); paper != "" {
+    options = "[" + paper + "paper]"
+  }
+
+  preamble := latexPreamble
+  if name := doc.Meta("preamble"); name != "" {
+    if !filepath.IsAbs(name) {
+      name = filepath.Join(filepath.Dir(doc.path), name)
+    }
+    content, err := ioutil.ReadFile(name)
+    if err != nil {
+      return nil, err
+    }
+    preamble = string(content)
+  }
+
+  out := []string{
+    fmt.Sprintf(`\documentclass%s{%s}`, options, class),
+    strings.TrimRight(preamble, "\n"),
+  }
+
+  title := doc.Meta("title")
+  if title != "" {
+    out = append(out, `\title{`+latexEscaper.Replace(title)+`}`)
+    out = append(out, `\author{`+latexEscaper.Replace(doc.Meta("author"))+`}`)
+    out = append(out, `\date{}`)
+  }
+  out = append(out, ``, `\begin{document}`)
+  if title != "" {
+    out = append(out, `\maketitle`)
+  }
+
+  section := `\section`
+  if latexChapters[class] {
+    section = `\chapter`
+  }
+
+  list := false
+  for _, para := range doc.Paragraphs() {
+    if list && para.style != Bullet {
+      out = append(out, `\end{itemize}`)
+      list = false
+    }
+    switch para.style {
+    case Heading:
+      out = append(out, ``, section+`{`+para.LaTeX()+`}`)
+    case Bullet:
+      if !list {
+        out = append(out, ``, `\begin{itemize}`)
+        list = true
+      }
+      out = append(out, `  \item `+para.LaTeX())
+    default:
+      out = append(out, ``, para.LaTeX())
+    }
+  }
+  if list {
+    out = append(out, `\end{itemize}`)
+  }
+
+  out = append(out, ``, `\end{document}`, ``)
+  return []byte(strings.Join(out, "\n")), nil
+}
+
+// LaTeX renders the paragraph's words with `` '' quotes and Emphasis
+// runs wrapped in \emph.
+func (self *ParaAPI) LaTeX() string {
+
+  words := self.Words()
+  parts := []string{}
+
+  for i, word := range words {
+    prev, next := neighbours(words, i)
+    prefix, suffix, gap := word.Affixes(prev, next)
+
+    if word.IsEmphasis() && (prev == nil || !prev.IsEmphasis()) {
+      parts = append(parts, `\emph{`)
+    }
+    prefix = strings.Replace(latexEscaper.Replace(prefix), `''`, "``", -1)
+    parts = append(parts, prefix+latexEscaper.Replace(word.Display())+latexEscaper.Replace(suffix))
+    if word.IsEmphasis() && (next == nil || !next.IsEmphasis()) {
+      parts = append(parts, `}`)
+    }
+
+    if next != nil {
+      parts = append(parts, gap)
+    }
+  }
+
+  return strings.Join(parts, "")
+}
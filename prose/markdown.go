@@ -0,0 +1,146 @@
+package prose
+
+import (
+  "regexp"
+  "strings"
+)
+
+var (
+  markdownEscaper = strings.NewReplacer(
+    "\\", "\\\\",
+    "*", "\\*",
+    "_", "\\_",
+    "`", "\\`",
+    "[", "\\[",
+    "]", "\\]",
+    "<", "\\<",
+  )
+  // text that Markdown would read as the start of a block
+  markdownBlock = regexp.MustCompile(`^([#>+=-]|\d+[.)])`)
+)
+
+func exportMarkdown(doc *DocAPI) ([]byte, error) {
+
+  out := []string{}
+  last := -1
+
+  for _, para := range doc.Paragraphs() {
+    if len(out) > 0 && !(para.style == Bullet && last == Bullet) {
+      out = append(out, "")
+    }
+    out = append(out, para.Markdown())
+    last = para.style
+  }
+
+  return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+// Markdown renders the paragraph's text as one markdown block.
+func (self *ParaAPI) Markdown() string {
+
+  words := self.Words()
+  parts := []string{}
+
+  for i, word := range words {
+    prev, next := neighbours(words, i)
+    prefix, suffix, gap := word.Affixes(prev, next)
+
+    str := prefix + markdownEscaper.Replace(word.Display()) + suffix
+
+    // emphasis wraps whole runs, quotes and punctuation included
+    if word.IsEmphasis() && (prev == nil || !prev.IsEmphasis()) {
+      str = "*" + str
+    }
+    if word.IsEmphasis() && (next == nil || !next.IsEmphasis()) {
+      str = str + "*"
+    }
+
+    parts = append(parts, str+gap)
+  }
+
+  line := strings.TrimSpace(strings.Join(parts, ""))
+
+  switch self.style {
+  case Heading:
+    return "# " + line
+  case Bullet:
+    return "- " + line
+  }
+
+  if loc := markdownBlock.FindStringIndex(line); loc != nil {
+    if line[0] >= '0' && line[0] <= '9' {
+      return line[:loc[1]-1] + "\\" + line[loc[1]-1:]
+    }
+    return "\\" + line
+  }
+  return line
+}
+
+var (
+  markdownHeading = regexp.MustCompile(`^ {0,3}#{1,6}(\s+|$)`)
+  markdownItem    = regexp.MustCompile(`^ {0,3}([-*+]|\d+[.)])\s+`)
+  markdownQuote   = regexp.MustCompile(`^ {0,3}>\s?`)
+  markdownRule    = regexp.MustCompile(`^ {0,3}([-*_])(\s*([-*_]))*\s*$`)
+  markdownFence   = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// importMarkdown maps ATX headings to Heading, list items to Bullet and
+// everything else to Content paragraphs split at blank lines.
+func importMarkdown(doc *DocAPI, content []byte) (*imported, error) {
+
+  paras := []*ParaAPI{}
+  style := Content
+  lines := []string{}
+
+  flush := func() {
+    if len(lines) > 0 {
+      para := newPara(doc)
+      para.style = style
+      para.Tokenize(strings.Join(lines, " "), true)
+      if !para.IsEmpty() {
+        paras = append(paras, para)
+      }
+    }
+    style = Content
+    lines = []string{}
+  }
+
+  str := strings.Replace(string(content), "\r\n", "\n", -1)
+
+  for _, line := range strings.Split(str, "\n") {
+
+    if markdownFence.MatchString(line) {
+      flush()
+      continue
+    }
+
+    if strings.TrimSpace(line) == "" || markdownRule.MatchString(line) && len(strings.TrimSpace(line)) >= 3 {
+      flush()
+      continue
+    }
+
+    if loc := markdownHeading.FindStringIndex(line); loc != nil {
+      flush()
+      style = Heading
+      lines = append(lines, strings.TrimRight(line[loc[1]:], " #"))
+      flush()
+      continue
+    }
+
+    if loc := markdownItem.FindStringIndex(line); loc != nil {
+      flush()
+      style = Bullet
+      lines = append(lines, line[loc[1]:])
+      continue
+    }
+
+    if loc := markdownQuote.FindStringIndex(line); loc != nil {
+      line = line[loc[1]:]
+    }
+
+    lines = append(lines, strings.TrimSpace(line))
+  }
+  flush()
+
+  return &imported{paras: paras}, nil
+}
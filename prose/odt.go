@@ -0,0 +1,192 @@
+package prose
+
+import (
+  "bytes"
+  "encoding/xml"
+  "errors"
+  "io"
+  "strconv"
+  "strings"
+)
+
+const (
+  odtText   = `urn:oasis:names:tc:opendocument:xmlns:text:1.0`
+  odtStyle  = `urn:oasis:names:tc:opendocument:xmlns:style:1.0`
+  odtOffice = `urn:oasis:names:tc:opendocument:xmlns:office:1.0`
+)
+
+type odtStyleInfo struct {
+  parent   string
+  heading  bool
+  list     bool
+  emphasis bool
+}
+
+// odtStyles adds the named and automatic styles of one package part.
+func odtStyles(data []byte, styles map[string]*odtStyleInfo) {
+
+  decoder := xml.NewDecoder(bytes.NewReader(data))
+  style := (*odtStyleInfo)(nil)
+
+  for {
+    token, err := decoder.Token()
+    if err != nil {
+      break
+    }
+    switch e := token.(type) {
+    case xml.StartElement:
+      if e.Name.Space != odtStyle {
+        continue
+      }
+      switch e.Name.Local {
+      case "style":
+        name := xmlAttr(e, "name")
+        display := strings.ToLower(xmlAttr(e, "display-name"))
+        if display == "" {
+          display = strings.ToLower(strings.Replace(name, "_20_", " ", -1))
+        }
+        style = &odtStyleInfo{
+          parent:  xmlAttr(e, "parent-style-name"),
+          heading: xmlAttr(e, "default-outline-level") != "" || strings.HasPrefix(display, "heading") || display == "title",
+          list:    xmlAttr(e, "list-style-name") != "" || strings.HasPrefix(display, "list"),
+        }
+        styles[name] = style
+      case "text-properties":
+        if style != nil {
+          italic := xmlAttr(e, "font-style")
+          weight := xmlAttr(e, "font-weight")
+          heavy, _ := strconv.Atoi(weight)
+          style.emphasis = italic == "italic" || italic == "oblique" || weight == "bold" || heavy >= 600
+        }
+      }
+    case xml.EndElement:
+      if e.Name.Space == odtStyle && e.Name.Local == "style" {
+        style = nil
+      }
+    }
+  }
+}
+
+// odtLookup resolves a style through its parents.
+func odtLookup(styles map[string]*odtStyleInfo, name string) odtStyleInfo {
+  found := odtStyleInfo{}
+  seen := map[string]bool{}
+  for style := styles[name]; style != nil && !seen[name]; style = styles[name] {
+    seen[name] = true
+    found.heading = found.heading || style.heading
+    found.list = found.list || style.list
+    found.emphasis = found.emphasis || style.emphasis
+    name = style.parent
+  }
+  return found
+}
+
+// importODT reads content.xml. Headings become Heading, paragraphs in
+// lists Bullet, and italic or bold spans Emphasis. Notes and comments
+// are left out.
+func importODT(doc *DocAPI, content []byte) (*imported, error) {
+
+  files, err := readZip(content)
+  if err != nil {
+    return nil, err
+  }
+  body, ok := files["content.xml"]
+  if !ok {
+    return nil, errors.New("no content.xml in package")
+  }
+  styles := map[string]*odtStyleInfo{}
+  odtStyles(files["styles.xml"], styles)
+  odtStyles(body, styles)
+
+  paras := []*ParaAPI{}
+  stack := []*richText{}
+  emphasis := []bool{false}
+  lists := 0
+
+  decoder := xml.NewDecoder(bytes.NewReader(body))
+  for {
+    token, err := decoder.Token()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return nil, err
+    }
+
+    text := (*richText)(nil)
+    if len(stack) > 0 {
+      text = stack[len(stack)-1]
+    }
+    emph := emphasis[len(emphasis)-1]
+
+    switch e := token.(type) {
+
+    case xml.StartElement:
+      if e.Name.Space == odtOffice && e.Name.Local == "annotation" {
+        decoder.Skip()
+        continue
+      }
+      if e.Name.Space != odtText {
+        continue
+      }
+      switch e.Name.Local {
+      case "note":
+        decoder.Skip()
+      case "list-item":
+        lists++
+      case "p", "h":
+        style := odtLookup(styles, xmlAttr(e, "style-name"))
+        para := &richText{style: Content}
+        switch {
+        case e.Name.Local == "h" || style.heading:
+          para.style = Heading
+        case lists > 0 || style.list:
+          para.style = Bullet
+        }
+        stack = append(stack, para)
+        emphasis = append(emphasis, false)
+      case "span":
+        emphasis = append(emphasis, emph || odtLookup(styles, xmlAttr(e, "style-name")).emphasis)
+      case "s":
+        count, err := strconv.Atoi(xmlAttr(e, "c"))
+        if err != nil || count < 1 {
+          count = 1
+        }
+        if text != nil {
+          text.Add(strings.Repeat(" ", count), emph)
+        }
+      case "tab", "line-break":
+        if text != nil {
+          text.Add(" ", emph)
+        }
+      }
+
+    case xml.EndElement:
+      if e.Name.Space != odtText {
+        continue
+      }
+      switch e.Name.Local {
+      case "list-item":
+        lists--
+      case "span":
+        emphasis = emphasis[:len(emphasis)-1]
+      case "p", "h":
+        if text == nil {
+          continue
+        }
+        stack = stack[:len(stack)-1]
+        emphasis = emphasis[:len(emphasis)-1]
+        if para := text.Para(doc); !para.IsEmpty() {
+          paras = append(paras, para)
+        }
+      }
+
+    case xml.CharData:
+      if text != nil {
+        text.Add(string(e), emph)
+      }
+    }
+  }
+
+  return &imported{paras: paras}, nil
+}
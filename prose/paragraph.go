@@ -0,0 +1,564 @@
+package prose
+
+import (
+  "container/list"
+  "image"
+  "image/color"
+  "sort"
+  "strings"
+)
+
+// Paragraph styles. Focus and Highlight are only colours, for the
+// editor's cursor and selection.
+const (
+  Heading int = iota
+  Content
+  Bullet
+  Comment
+  Quote
+  Focus
+  Highlight
+)
+
+// FontSizes and FontColors are each style's relative text size and its
+// colour, shared by the editor and the exporters that mimic it.
+var (
+  FontSizes  map[int]float64
+  FontColors map[int]color.RGBA
+)
+
+func init() {
+
+  FontSizes = map[int]float64{
+    Heading: 4.0,
+    Content: 3.0,
+  }
+  FontSizes[Bullet] = FontSizes[Content]
+  FontSizes[Comment] = FontSizes[Content]
+
+  FontColors = map[int]color.RGBA{
+    Focus:     color.RGBA{200, 200, 0, 255},
+    Heading:   color.RGBA{255, 255, 255, 255},
+    Content:   color.RGBA{200, 200, 200, 255},
+    Comment:   color.RGBA{100, 100, 100, 255},
+    Quote:     color.RGBA{150, 200, 150, 255},
+    Highlight: color.RGBA{255, 255, 255, 255},
+  }
+  FontColors[Bullet] = FontColors[Content]
+}
+
+// ParaAPI is a paragraph: a style and a list of words with a cursor.
+type ParaAPI struct {
+  id    uint64
+  doc   *DocAPI
+  list  *list.List
+  node  *list.Element
+  style int
+}
+
+func newPara(doc *DocAPI) *ParaAPI {
+  self := &ParaAPI{}
+  self.id = id()
+  self.doc = doc
+  self.list = list.New()
+  self.node = self.list.PushFront(newWord(self))
+  self.style = Content
+  return self
+}
+
+func (self *ParaAPI) check() {
+
+  // only the focused word keeps a caret away from its end
+  discard := []*list.Element{}
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    if e != self.node {
+      e.Value.(*WordAPI).CaretEnd()
+    }
+    if e != self.node && e.Value.(*WordAPI).Len() == 0 {
+      discard = append(discard, e)
+    }
+  }
+  for _, e := range discard {
+    self.list.Remove(e)
+  }
+
+  if self.list.Len() == 0 {
+    self.list.PushFront(newWord(self))
+  }
+
+  if self.node == nil {
+    self.node = self.list.Front()
+  }
+}
+
+// IsEmpty reports whether the paragraph holds no text.
+func (self *ParaAPI) IsEmpty() bool {
+  return self.Len() == 0 || (self.Len() == 1 && self.Word().IsEmpty())
+}
+
+// IsStart reports whether the cursor is on the first word.
+func (self *ParaAPI) IsStart() bool {
+  return self.node.Prev() == nil
+}
+
+// IsEnd reports whether the cursor is on the last word.
+func (self *ParaAPI) IsEnd() bool {
+  return self.node.Next() == nil
+}
+
+// Len counts the words, including any empty one under the cursor.
+func (self *ParaAPI) Len() int {
+  return self.list.Len()
+}
+
+// Style returns the paragraph style, such as Heading or Content.
+func (self *ParaAPI) Style() int {
+  return self.style
+}
+
+// Word returns the word under the cursor.
+func (self *ParaAPI) Word() *WordAPI {
+  return self.node.Value.(*WordAPI)
+}
+
+// Clean moves the cursor off an empty word onto a neighbour, so the
+// empty one is discarded.
+func (self *ParaAPI) Clean() {
+  if self.Word().IsEmpty() {
+    self.Left()
+  }
+  if self.Word().IsEmpty() {
+    self.Right()
+  }
+  if self.Word().IsEmpty() {
+    self.node = nil
+  }
+  self.check()
+}
+
+func (self *ParaAPI) prevNext() (*WordAPI, *WordAPI) {
+  prev := (*WordAPI)(nil)
+  next := (*WordAPI)(nil)
+
+  if self.node.Prev() != nil {
+    prev = self.node.Prev().Value.(*WordAPI)
+  }
+
+  if self.node.Next() != nil {
+    next = self.node.Next().Value.(*WordAPI)
+  }
+
+  return prev, next
+}
+
+// Export encodes the paragraph for the document file: a paragraph line
+// with its style, then a line per word.
+func (self *ParaAPI) Export() []string {
+  words := []string{}
+
+  flags := []string{
+    "paragraph",
+  }
+
+  if self.style == Heading {
+    flags = append(flags, "heading")
+  }
+
+  if self.style == Bullet {
+    flags = append(flags, "bullet")
+  }
+
+  words = append(words, strings.Join(flags, " "))
+
+  prev := (*WordAPI)(nil)
+  next := (*WordAPI)(nil)
+
+  for e := self.list.Front(); e != nil; e = e.Next() {
+
+    next = nil
+    if e.Next() != nil {
+      next = e.Next().Value.(*WordAPI)
+    }
+
+    word := e.Value.(*WordAPI)
+    if !word.IsEmpty() {
+      words = append(words, word.Export(prev, next))
+    }
+
+    prev = word
+  }
+  return words
+}
+
+// Import decodes lines written by Export, leaving the cursor on the
+// first word.
+func (self *ParaAPI) Import(words []string) {
+  defer self.Top()
+  defer self.check()
+
+  for _, line := range words {
+    if strings.HasPrefix(line, "paragraph") {
+      if strings.Contains(line, "heading") {
+        self.style = Heading
+      }
+      if strings.Contains(line, "bullet") {
+        self.style = Bullet
+      }
+      continue
+    }
+    self.node = self.list.PushBack(newWord(self))
+    self.Word().Import(line)
+  }
+}
+
+// Top moves the cursor to the first word.
+func (self *ParaAPI) Top() {
+  defer self.check()
+  self.node = self.list.Front()
+}
+
+// Bottom moves the cursor to the last word.
+func (self *ParaAPI) Bottom() {
+  defer self.check()
+  self.node = self.list.Back()
+}
+
+// reach narrows a word's position to its central third and stretches it
+// over the lines above (dir -1) or below (dir 1).
+func reach(fpos image.Rectangle, dir int, line int) image.Rectangle {
+  third := fpos.Dx() / 3
+  fpos = image.Rect(fpos.Min.X+third, fpos.Min.Y, fpos.Max.X-third, fpos.Max.Y)
+  fpos = fpos.Add(image.Pt(0, line*dir))
+  if dir < 0 {
+    fpos.Min.Y -= line * 10
+  } else {
+    fpos.Max.Y += line * 10
+  }
+  return fpos
+}
+
+// Up moves the cursor to a word on the line above fpos, reporting false
+// on the first line.
+func (self *ParaAPI) Up(fpos image.Rectangle, layout Layout) bool {
+  defer self.check()
+
+  fpos = reach(fpos, -1, layout.LineHeight(self))
+
+  for e := self.list.Back(); e != nil; e = e.Prev() {
+    word := e.Value.(*WordAPI)
+    if fpos.Overlaps(layout.Word(word)) {
+      self.node = e
+      return true
+    }
+  }
+
+  return false
+}
+
+// Down moves the cursor to a word on the line below fpos, reporting false
+// on the last line.
+func (self *ParaAPI) Down(fpos image.Rectangle, layout Layout) bool {
+  defer self.check()
+
+  fpos = reach(fpos, 1, layout.LineHeight(self))
+
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    word := e.Value.(*WordAPI)
+    if fpos.Overlaps(layout.Word(word)) {
+      self.node = e
+      return true
+    }
+  }
+
+  return false
+}
+
+// Left moves the cursor to the word before, or opens an empty word
+// before the first.
+func (self *ParaAPI) Left() bool {
+  defer self.check()
+  if self.node.Prev() != nil {
+    self.node = self.node.Prev()
+    return true
+  }
+  if !self.Word().IsEmpty() {
+    self.node = self.list.InsertBefore(newWord(self), self.node)
+    return true
+  }
+  return false
+}
+
+// Right moves the cursor to the word after, or opens an empty word after
+// the last.
+func (self *ParaAPI) Right() bool {
+  defer self.check()
+  if self.node.Next() != nil {
+    self.node = self.node.Next()
+    return true
+  }
+  if !self.Word().IsEmpty() {
+    self.Space()
+    return true
+  }
+  return false
+}
+
+// Insert adds str at the caret of the word under the cursor.
+func (self *ParaAPI) Insert(str string) {
+  self.Word().Insert(str)
+}
+
+// Space starts a new word after the cursor.
+func (self *ParaAPI) Space() {
+  defer self.check()
+  self.node = self.list.InsertAfter(newWord(self), self.node)
+  self.Word().Smart(self.prevNext())
+}
+
+// BackSpace deletes the character before the caret or, at the start of
+// a word, moves to the word before.
+func (self *ParaAPI) BackSpace() {
+  defer self.check()
+  if !self.Word().BackSpace() {
+    self.node = self.node.Prev()
+  }
+}
+
+// Delete deletes the character after the caret or, at the end of a word,
+// the gap after it, joining the next word on.
+func (self *ParaAPI) Delete() {
+  defer self.check()
+  if self.Word().Delete() {
+    return
+  }
+  if next := self.node.Next(); next != nil {
+    self.Word().Join(next.Value.(*WordAPI))
+    self.list.Remove(next)
+  }
+}
+
+// DeleteWord clears the word under the cursor or, if it is already
+// empty, moves to the word before.
+func (self *ParaAPI) DeleteWord() {
+  defer self.check()
+  if !self.Word().Clear() {
+    self.node = self.node.Prev()
+  }
+}
+
+// CaretLeft moves the caret back a character within the word.
+func (self *ParaAPI) CaretLeft() bool {
+  return self.Word().CaretLeft()
+}
+
+// CaretRight moves the caret on a character within the word.
+func (self *ParaAPI) CaretRight() bool {
+  return self.Word().CaretRight()
+}
+
+// DQuote toggles quotation marks on the word under the cursor.
+func (self *ParaAPI) DQuote() {
+  self.Word().DQuote()
+}
+
+// Period toggles a full stop after the word under the cursor.
+func (self *ParaAPI) Period() {
+  self.Word().Period()
+}
+
+// Comma toggles a comma after the word under the cursor.
+func (self *ParaAPI) Comma() {
+  self.Word().Comma()
+}
+
+// Exclaim toggles an exclamation mark after the word under the cursor.
+func (self *ParaAPI) Exclaim() {
+  self.Word().Exclaim()
+}
+
+// Question toggles a question mark after the word under the cursor.
+func (self *ParaAPI) Question() {
+  self.Word().Question()
+}
+
+// Hyphen toggles a hyphen after the word under the cursor.
+func (self *ParaAPI) Hyphen() {
+  self.Word().Hyphen()
+}
+
+// Paren toggles parentheses on the word under the cursor.
+func (self *ParaAPI) Paren() {
+  self.Word().Paren()
+}
+
+// Colon toggles a colon after the word under the cursor.
+func (self *ParaAPI) Colon() {
+  self.Word().Colon()
+}
+
+// SemiColon toggles a semicolon after the word under the cursor.
+func (self *ParaAPI) SemiColon() {
+  self.Word().SemiColon()
+}
+
+// UCFirst capitalises the word under the cursor.
+func (self *ParaAPI) UCFirst() {
+  self.Word().UCFirst()
+}
+
+// Heading makes the paragraph a heading, or content if it is one.
+func (self *ParaAPI) Heading() {
+  if self.style != Heading {
+    self.style = Heading
+    return
+  }
+  self.style = Content
+}
+
+// Bullet makes the paragraph a bullet point, or content if it is one.
+func (self *ParaAPI) Bullet() {
+  if self.style != Bullet {
+    self.style = Bullet
+    return
+  }
+  self.style = Content
+}
+
+// Emphasis toggles emphasis on the word under the cursor.
+func (self *ParaAPI) Emphasis() {
+  self.Word().Emphasis()
+}
+
+// Home moves the cursor to the first word on its line.
+func (self *ParaAPI) Home(layout Layout) {
+  defer self.check()
+
+  fpos := layout.Word(self.Word())
+
+  for e := self.node; e != nil; e = e.Prev() {
+    self.node = e
+    if e.Prev() != nil {
+      word := e.Prev().Value.(*WordAPI)
+      if layout.Word(word).Min.Y < fpos.Min.Y {
+        break
+      }
+    }
+  }
+}
+
+// End moves the cursor to the last word on its line.
+func (self *ParaAPI) End(layout Layout) {
+  defer self.check()
+
+  fpos := layout.Word(self.Word())
+
+  for e := self.node; e != nil; e = e.Next() {
+    self.node = e
+    if e.Next() != nil {
+      word := e.Next().Value.(*WordAPI)
+      if layout.Word(word).Min.Y > fpos.Min.Y {
+        break
+      }
+    }
+  }
+}
+
+// Variable toggles whether the word under the cursor names a variable.
+func (self *ParaAPI) Variable() {
+  self.Word().Variable()
+}
+
+// WordList returns the distinct words of at least min characters,
+// sorted, with accents composed so each word is listed once.
+func (self *ParaAPI) WordList(min int) []string {
+  words := map[string]struct{}{}
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    word := e.Value.(*WordAPI)
+    if !word.IsEmpty() && word.Len() >= min {
+      words[Compose(word.text)] = struct{}{}
+    }
+  }
+  list := []string{}
+  for word, _ := range words {
+    list = append(list, word)
+  }
+  sort.Strings(list)
+  return list
+}
+
+// All returns every word in order, including the empty one a cursor
+// may be sitting on, as the editor draws them.
+func (self *ParaAPI) All() []*WordAPI {
+  words := []*WordAPI{}
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    words = append(words, e.Value.(*WordAPI))
+  }
+  return words
+}
+
+// Words returns the non-empty words in order, as exporters see them.
+func (self *ParaAPI) Words() []*WordAPI {
+  words := []*WordAPI{}
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    word := e.Value.(*WordAPI)
+    if !word.IsEmpty() {
+      words = append(words, word)
+    }
+  }
+  return words
+}
+
+// AddWord inserts word after the cursor and moves onto it.
+func (self *ParaAPI) AddWord(word *WordAPI) {
+  defer self.check()
+  word.Reparent(self)
+  self.node = self.list.InsertAfter(word, self.node)
+}
+
+// Join moves every word of next onto the end of this paragraph, leaving
+// the cursor where it was.
+func (self *ParaAPI) Join(next *ParaAPI) {
+  defer self.check()
+  for _, word := range next.Words() {
+    word.Reparent(self)
+    self.list.PushBack(word)
+  }
+  next.list = list.New()
+  next.node = nil
+  next.check()
+}
+
+// remove takes words out of the paragraph, leaving the cursor on the
+// word before the first of them, or on an empty word in their place if
+// they began the paragraph.
+func (self *ParaAPI) remove(words map[*WordAPI]bool) {
+  defer self.check()
+
+  found := false
+  for e := self.list.Front(); e != nil; {
+    next := e.Next()
+    if words[e.Value.(*WordAPI)] {
+      if !found {
+        found = true
+        self.node = e.Prev()
+        if self.node == nil {
+          self.node = self.list.PushFront(newWord(self))
+        }
+      }
+      self.list.Remove(e)
+    }
+    e = next
+  }
+}
+
+// Split moves the words from the cursor on into next.
+func (self *ParaAPI) Split(next *ParaAPI) {
+  defer self.check()
+  for self.node != nil && !self.Word().IsEmpty() {
+    next.AddWord(self.Word())
+    node := self.node
+    next := self.node.Next()
+    self.list.Remove(node)
+    self.node = next
+  }
+}
@@ -0,0 +1,331 @@
+package prose
+
+import (
+  "bytes"
+  "compress/zlib"
+  "fmt"
+  "sort"
+  "strings"
+  "unicode/utf16"
+)
+
+// Paper sizes in points, chosen with "meta paper letter".
+var pdfPapers = map[string][2]float64{
+  "a4":     {595.28, 841.89},
+  "letter": {612, 792},
+}
+
+const (
+  pdfMargin = 72.0
+  pdfSize   = 12.0 // Content text in points; other styles scale by FontSizes
+  pdfSkew   = 0.2  // synthetic oblique for Emphasis
+)
+
+type pdfRun struct {
+  x, y    float64 // baseline, from the top left of the page
+  text    string
+  size    float64
+  gray    float64
+  oblique bool
+}
+
+type pdfLine struct {
+  runs   []pdfRun
+  height float64
+}
+
+// pdfLayout flows the document onto pages with the same line and
+// paragraph spacing ratios the editor draws with.
+type pdfLayout struct {
+  font   *ttfFont
+  width  float64
+  height float64
+  pages  [][]pdfRun
+  y      float64
+}
+
+func (self *pdfLayout) newPage() {
+  self.pages = append(self.pages, []pdfRun{})
+  self.y = pdfMargin
+}
+
+func (self *pdfLayout) lineHeight(size float64) float64 {
+  return float64(self.font.ascent-self.font.descent) * size / float64(self.font.unitsPerEm)
+}
+
+// lines breaks a paragraph at word boundaries to fit the text width.
+func (self *pdfLayout) lines(para *ParaAPI) []pdfLine {
+
+  size := pdfSize * FontSizes[para.style] / FontSizes[Content]
+  height := self.lineHeight(size)
+  width := self.width - pdfMargin*2
+
+  indent := 0.0
+  lines := []pdfLine{{height: height}}
+
+  if para.style == Bullet {
+    lines[0].runs = append(lines[0].runs, pdfRun{text: "•", size: size})
+    indent = self.font.Width("• ", size)
+  }
+
+  x := indent
+  words := para.Words()
+  for i, word := range words {
+    prev, next := neighbours(words, i)
+    prefix, suffix, gap := word.Affixes(prev, next)
+
+    text := prefix + word.Display() + suffix
+    w := self.font.Width(text, size)
+
+    if x > indent && x+w > width {
+      lines = append(lines, pdfLine{height: height})
+      x = indent
+    }
+
+    run := pdfRun{x: x, text: text, size: size}
+    if word.IsParen() {
+      run.gray = 0.4
+    }
+    run.oblique = word.IsEmphasis()
+
+    last := &lines[len(lines)-1]
+    last.runs = append(last.runs, run)
+    x += w + self.font.Width(gap, size)
+  }
+  return lines
+}
+
+func (self *pdfLayout) place(paras []*ParaAPI) {
+
+  self.newPage()
+  bottom := self.height - pdfMargin
+
+  for i, para := range paras {
+    lines := self.lines(para)
+    advance := lines[0].height * 1.2
+
+    // keep headings with the first line that follows
+    need := lines[0].height
+    if para.style == Heading {
+      need = advance * float64(len(lines))
+      if i+1 < len(paras) {
+        need += self.lineHeight(pdfSize) * 1.5
+      }
+    }
+    if self.y+need > bottom && self.y > pdfMargin {
+      self.newPage()
+    }
+
+    for j, line := range lines {
+      if self.y+line.height > bottom {
+        self.newPage()
+      }
+      baseline := self.y + float64(self.font.ascent)*line.runs[0].size/float64(self.font.unitsPerEm)
+      page := &self.pages[len(self.pages)-1]
+      for _, run := range line.runs {
+        run.x += pdfMargin
+        run.y = baseline
+        *page = append(*page, run)
+      }
+      if j < len(lines)-1 {
+        self.y += advance
+      }
+    }
+    self.y += lines[len(lines)-1].height * 1.5
+  }
+
+  for i := range self.pages {
+    num := fmt.Sprintf("%d", i+1)
+    size := pdfSize * 0.8
+    self.pages[i] = append(self.pages[i], pdfRun{
+      x:    (self.width - self.font.Width(num, size)) / 2,
+      y:    self.height - pdfMargin/2,
+      text: num,
+      size: size,
+      gray: 0.4,
+    })
+  }
+}
+
+// pdfString encodes document metadata as a UTF-16 text string.
+func pdfString(str string) string {
+  out := "<FEFF"
+  for _, u := range utf16.Encode([]rune(str)) {
+    out += fmt.Sprintf("%04X", u)
+  }
+  return out + ">"
+}
+
+type pdfWriter struct {
+  buf     bytes.Buffer
+  offsets []int
+}
+
+func (self *pdfWriter) reserve() int {
+  self.offsets = append(self.offsets, 0)
+  return len(self.offsets)
+}
+
+func (self *pdfWriter) object(id int, body string) {
+  self.offsets[id-1] = self.buf.Len()
+  fmt.Fprintf(&self.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (self *pdfWriter) stream(id int, dict string, data []byte) {
+  var z bytes.Buffer
+  w := zlib.NewWriter(&z)
+  w.Write(data)
+  w.Close()
+  self.offsets[id-1] = self.buf.Len()
+  fmt.Fprintf(&self.buf, "%d 0 obj\n<< /Length %d /Filter /FlateDecode %s >>\nstream\n", id, z.Len(), dict)
+  self.buf.Write(z.Bytes())
+  fmt.Fprintf(&self.buf, "\nendstream\nendobj\n")
+}
+
+func exportPDF(doc *DocAPI) ([]byte, error) {
+
+  font, err := loadTTF(doc.opts.Font)
+  if err != nil {
+    return nil, fmt.Errorf("font %s: %v", doc.opts.Font, err)
+  }
+
+  paper := strings.ToLower(doc.Meta("paper"))
+  if paper == "" {
+    paper = "a4"
+  }
+  dims, ok := pdfPapers[paper]
+  if !ok {
+    return nil, fmt.Errorf("unknown paper size: %s", paper)
+  }
+
+  layout := &pdfLayout{font: font, width: dims[0], height: dims[1]}
+  layout.place(doc.Paragraphs())
+
+  pdf := &pdfWriter{}
+  pdf.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+  catalog := pdf.reserve()
+  pages := pdf.reserve()
+  type0 := pdf.reserve()
+  cidfont := pdf.reserve()
+  descriptor := pdf.reserve()
+  fontfile := pdf.reserve()
+  tounicode := pdf.reserve()
+  info := pdf.reserve()
+
+  used := map[uint16]rune{}
+  kids := []string{}
+
+  for _, runs := range layout.pages {
+    content := []string{"BT"}
+    for _, run := range runs {
+      glyphs := ""
+      for _, r := range run.text {
+        g := font.Glyph(r)
+        used[g] = r
+        glyphs += fmt.Sprintf("%04X", g)
+      }
+      skew := 0.0
+      if run.oblique {
+        skew = pdfSkew
+      }
+      content = append(content,
+        fmt.Sprintf("/F1 %.2f Tf %.2f g", run.size, run.gray),
+        fmt.Sprintf("1 0 %.2f 1 %.2f %.2f Tm <%s> Tj", skew, run.x, dims[1]-run.y, glyphs),
+      )
+    }
+    content = append(content, "ET")
+
+    page := pdf.reserve()
+    stream := pdf.reserve()
+    pdf.stream(stream, "", []byte(strings.Join(content, "\n")))
+    pdf.object(page, fmt.Sprintf(
+      "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+      pages, dims[0], dims[1], type0, stream,
+    ))
+    kids = append(kids, fmt.Sprintf("%d 0 R", page))
+  }
+
+  gids := []int{}
+  for g, _ := range used {
+    gids = append(gids, int(g))
+  }
+  sort.Ints(gids)
+
+  widths := []string{}
+  cmap := []string{}
+  for _, g := range gids {
+    adv := 0
+    if g < len(font.advances) {
+      adv = font.advances[g]
+    }
+    widths = append(widths, fmt.Sprintf("%d [%d]", g, font.scale(adv)))
+    utf := ""
+    for _, u := range utf16.Encode([]rune{used[uint16(g)]}) {
+      utf += fmt.Sprintf("%04X", u)
+    }
+    cmap = append(cmap, fmt.Sprintf("<%04X> <%s>", g, utf))
+  }
+
+  unicode := []string{
+    "/CIDInit /ProcSet findresource begin",
+    "12 dict begin",
+    "begincmap",
+    "/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def",
+    "/CMapName /Adobe-Identity-UCS def",
+    "/CMapType 2 def",
+    "1 begincodespacerange",
+    "<0000> <FFFF>",
+    "endcodespacerange",
+  }
+  for len(cmap) > 0 {
+    n := len(cmap)
+    if n > 100 {
+      n = 100
+    }
+    unicode = append(unicode, fmt.Sprintf("%d beginbfchar", n))
+    unicode = append(unicode, cmap[:n]...)
+    unicode = append(unicode, "endbfchar")
+    cmap = cmap[n:]
+  }
+  unicode = append(unicode,
+    "endcmap",
+    "CMapName currentdict /CMap defineresource pop",
+    "end",
+    "end",
+  )
+
+  pdf.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+  pdf.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+  pdf.object(type0, fmt.Sprintf(
+    "<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+    font.name, cidfont, tounicode,
+  ))
+  pdf.object(cidfont, fmt.Sprintf(
+    "<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+    font.name, descriptor, strings.Join(widths, " "),
+  ))
+  pdf.object(descriptor, fmt.Sprintf(
+    "<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle %.1f /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+    font.name, font.scale(font.bbox[0]), font.scale(font.bbox[1]), font.scale(font.bbox[2]), font.scale(font.bbox[3]),
+    font.italic, font.scale(font.ascent), font.scale(font.descent), font.scale(font.capHeight), fontfile,
+  ))
+  pdf.stream(fontfile, fmt.Sprintf("/Length1 %d", len(font.data)), font.data)
+  pdf.stream(tounicode, "", []byte(strings.Join(unicode, "\n")))
+
+  meta := fmt.Sprintf("/Title %s /Producer %s", pdfString(doc.Title()), pdfString("prose"))
+  if author := doc.Meta("author"); author != "" {
+    meta += " /Author " + pdfString(author)
+  }
+  pdf.object(info, "<< "+meta+" >>")
+
+  xref := pdf.buf.Len()
+  fmt.Fprintf(&pdf.buf, "xref\n0 %d\n0000000000 65535 f \n", len(pdf.offsets)+1)
+  for _, off := range pdf.offsets {
+    fmt.Fprintf(&pdf.buf, "%010d 00000 n \n", off)
+  }
+  fmt.Fprintf(&pdf.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+    len(pdf.offsets)+1, catalog, info, xref)
+
+  return pdf.buf.Bytes(), nil
+}
@@ -0,0 +1,53 @@
+// Package prose is the document model behind the prose editor: words
+// carrying punctuation and style as flags, grouped into paragraphs,
+// grouped into a document with undo, crash journalling, and import and
+// export of foreign formats. It has no GUI dependencies; the SDL editor
+// is one client of it and the headless commands another.
+//
+// A document is edited through DocAPI, whose methods act at the cursor
+// the way the editor's keys do:
+//
+//   doc, err := prose.NewDoc("novel.prose", prose.DefaultOptions())
+//   doc.Insert("Hello")
+//   doc.Period()
+//   err = doc.Save()
+//
+// Tools that only read use Open, which keeps no journal.
+package prose
+
+import (
+  "image"
+  "log"
+  "sync/atomic"
+)
+
+var sequence uint64
+
+// Options are a document's settings that do not belong in its file.
+type Options struct {
+  Backups int    // timestamped copies Save keeps beside the file
+  Font    string // TrueType font PDF export lays out with and embeds
+}
+
+// DefaultOptions keeps five backups and embeds DejaVu Sans in PDFs.
+func DefaultOptions() Options {
+  return Options{
+    Backups: 5,
+    Font:    "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+  }
+}
+
+// Layout is provided by a front end to say where it last drew each
+// word, which is all Up, Down, Home and End need to move by line.
+type Layout interface {
+  Word(word *WordAPI) image.Rectangle
+  LineHeight(para *ParaAPI) int
+}
+
+func note(arg ...interface{}) {
+  log.Println(arg...)
+}
+
+func id() uint64 {
+  return atomic.AddUint64(&sequence, 1)
+}
@@ -0,0 +1,142 @@
+package prose
+
+import (
+  "fmt"
+  "io"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+  "time"
+)
+
+const (
+  backupStamp    = "20060102-150405"
+  backupInterval = 10 * time.Minute
+)
+
+// writeAtomic replaces path with data such that a crash at any point
+// leaves either the old file or the new one, never a truncated mix.
+func writeAtomic(path string, data []byte) error {
+
+  dir, base := filepath.Split(path)
+  if dir == "" {
+    dir = "."
+  }
+
+  tmp, err := ioutil.TempFile(dir, "."+base+".tmp")
+  if err != nil {
+    return err
+  }
+
+  fail := func(err error) error {
+    tmp.Close()
+    os.Remove(tmp.Name())
+    return err
+  }
+
+  if _, err := tmp.Write(data); err != nil {
+    return fail(err)
+  }
+  if err := tmp.Sync(); err != nil {
+    return fail(err)
+  }
+  // keep the permissions of the file being replaced
+  mode := os.FileMode(0644)
+  if info, err := os.Stat(path); err == nil {
+    mode = info.Mode().Perm()
+  }
+  if err := tmp.Chmod(mode); err != nil {
+    return fail(err)
+  }
+  if err := tmp.Close(); err != nil {
+    os.Remove(tmp.Name())
+    return err
+  }
+  if err := os.Rename(tmp.Name(), path); err != nil {
+    os.Remove(tmp.Name())
+    return err
+  }
+
+  // make the rename itself durable
+  if d, err := os.Open(dir); err == nil {
+    d.Sync()
+    d.Close()
+  }
+  return nil
+}
+
+func backupList(path string) []string {
+  names, _ := filepath.Glob(path + ".*.bak")
+  sort.Strings(names)
+  return names
+}
+
+// backup preserves the current on-disk copy of path as a timestamped
+// sibling before it is replaced, then prunes all but the newest keep
+// backups. Autosave runs every minute, so a new backup is only taken
+// once the newest is older than backupInterval.
+func backup(path string, keep int) error {
+
+  if keep <= 0 {
+    return nil
+  }
+
+  if _, err := os.Stat(path); err != nil {
+    if os.IsNotExist(err) {
+      return nil
+    }
+    return err
+  }
+
+  now := time.Now()
+
+  names := backupList(path)
+  if len(names) > 0 {
+    stamp := strings.TrimSuffix(strings.TrimPrefix(names[len(names)-1], path+"."), ".bak")
+    if last, err := time.ParseInLocation(backupStamp, stamp, time.Local); err == nil && now.Sub(last) < backupInterval {
+      return nil
+    }
+  }
+
+  name := fmt.Sprintf("%s.%s.bak", path, now.Format(backupStamp))
+
+  // a hard link costs nothing and survives the rename over path
+  if err := os.Link(path, name); err != nil {
+    if err := copyFile(path, name); err != nil {
+      return err
+    }
+  }
+
+  names = backupList(path)
+  for len(names) > keep {
+    os.Remove(names[0])
+    names = names[1:]
+  }
+  return nil
+}
+
+func copyFile(src string, dst string) error {
+  in, err := os.Open(src)
+  if err != nil {
+    return err
+  }
+  defer in.Close()
+
+  out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+  if err != nil {
+    return err
+  }
+  if _, err := io.Copy(out, in); err != nil {
+    out.Close()
+    os.Remove(dst)
+    return err
+  }
+  if err := out.Sync(); err != nil {
+    out.Close()
+    os.Remove(dst)
+    return err
+  }
+  return out.Close()
+}
@@ -0,0 +1,103 @@
+package prose
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+// A document whose file could not be read has no path to save to, and
+// must say so once it holds work.
+func TestSaveWithoutPath(t *testing.T) {
+  dir := t.TempDir()
+
+  doc, err := NewDoc(dir, DefaultOptions())
+  if err == nil {
+    t.Fatal("loading a directory did not fail")
+  }
+  if err := doc.Save(); err != nil {
+    t.Errorf("untouched document: %v", err)
+  }
+
+  typeParas(doc, "lost words")
+  if err := doc.Save(); err == nil {
+    t.Error("edited document without a path reported saved")
+  }
+
+  if err := doc.ReSave(filepath.Join(dir, "found.prose")); err != nil {
+    t.Fatal(err)
+  }
+  if err := doc.Save(); err != nil {
+    t.Errorf("after save as: %v", err)
+  }
+}
+
+func TestSaveKeepsMode(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "novel.prose")
+
+  doc, err := NewDoc(path, DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+  typeParas(doc, "private words")
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+  if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+    t.Errorf("new file mode %v, want 0644", info.Mode().Perm())
+  }
+
+  if err := os.Chmod(path, 0600); err != nil {
+    t.Fatal(err)
+  }
+  typeParas(doc, "more")
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+  if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+    t.Errorf("saved file mode %v, want 0600", info.Mode().Perm())
+  }
+}
+
+// An idle autosave must not rotate real backups out for copies of
+// unchanged content.
+func TestSaveIdle(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "novel.prose")
+  opts := DefaultOptions()
+  opts.Backups = 2
+
+  doc, err := NewDoc(path, opts)
+  if err != nil {
+    t.Fatal(err)
+  }
+  typeParas(doc, "written once")
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+
+  old := []string{path + ".20000101-000000.bak", path + ".20000102-000000.bak"}
+  for _, name := range old {
+    if err := ioutil.WriteFile(name, []byte(name), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  for i := 0; i < 3; i++ {
+    if err := doc.Save(); err != nil {
+      t.Fatal(err)
+    }
+  }
+  if got := backupList(path); strings.Join(got, " ") != strings.Join(old, " ") {
+    t.Errorf("idle saves left backups %v, want %v", got, old)
+  }
+
+  typeParas(doc, "then more")
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+  if got := backupList(path); len(got) != 2 || got[0] != old[1] {
+    t.Errorf("saving an edit left backups %v", got)
+  }
+}
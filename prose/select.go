@@ -0,0 +1,151 @@
+package prose
+
+// A selection runs from an anchor word to the cursor's word, in either
+// direction and across paragraphs. Moving the cursor other than by
+// SelectLeft and SelectRight drops it.
+
+// Deselect drops the selection, leaving the cursor where it is.
+func (self *DocAPI) Deselect() {
+  self.anchor = nil
+}
+
+// mark anchors a selection at the cursor if there is none yet. An empty
+// word is about to be discarded, so the anchor goes on a neighbour.
+func (self *DocAPI) mark() {
+  if self.Selection() == nil {
+    self.Paragraph().Clean()
+    self.anchor = self.Paragraph().Word()
+  }
+}
+
+// Selection returns the selected words in document order, or nil.
+func (self *DocAPI) Selection() []*WordAPI {
+  if self.anchor == nil {
+    return nil
+  }
+
+  focus := self.Paragraph().Word()
+  words := []*WordAPI{}
+  inside := false
+
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    for _, word := range e.Value.(*ParaAPI).All() {
+      edge := word == self.anchor || word == focus
+      if edge || inside {
+        words = append(words, word)
+      }
+      if edge && (inside || self.anchor == focus) {
+        return words
+      }
+      if edge {
+        inside = true
+      }
+    }
+  }
+
+  // the anchor word is gone
+  self.anchor = nil
+  return nil
+}
+
+// spanned returns the paragraphs the selection touches, in order.
+func (self *DocAPI) spanned() []*ParaAPI {
+  paras := []*ParaAPI{}
+  for _, word := range self.Selection() {
+    if len(paras) == 0 || paras[len(paras)-1] != word.para {
+      paras = append(paras, word.para)
+    }
+  }
+  return paras
+}
+
+// SelectLeft extends the selection to the word before, across
+// paragraphs, starting one at the cursor if there is none.
+func (self *DocAPI) SelectLeft() bool {
+  defer self.check()
+  self.mark()
+
+  if !self.Paragraph().IsStart() {
+    return self.Paragraph().Left()
+  }
+  if self.node.Prev() != nil {
+    self.node = self.node.Prev()
+    self.Paragraph().Bottom()
+    return true
+  }
+  return false
+}
+
+// SelectRight extends the selection to the word after, across
+// paragraphs, starting one at the cursor if there is none.
+func (self *DocAPI) SelectRight() bool {
+  defer self.check()
+  self.mark()
+
+  if !self.Paragraph().IsEnd() {
+    return self.Paragraph().Right()
+  }
+  if self.node.Next() != nil {
+    self.node = self.node.Next()
+    self.Paragraph().Top()
+    return true
+  }
+  return false
+}
+
+// flagSelection sets a flag on every selected word, or clears it if all
+// have it already. It reports false when there is no selection.
+func (self *DocAPI) flagSelection(flag uint64) bool {
+  words := self.Selection()
+  if words == nil {
+    return false
+  }
+
+  all := true
+  for _, word := range words {
+    all = all && word.Is(flag)
+  }
+  for _, word := range words {
+    if all {
+      word.Clr(flag)
+    } else {
+      word.Set(flag)
+    }
+  }
+  return true
+}
+
+// deleteSelection removes the selected words, joining what is left of
+// the first and last paragraphs, and leaves the cursor where they were.
+func (self *DocAPI) deleteSelection() bool {
+  words := self.Selection()
+  if words == nil {
+    return false
+  }
+  defer self.check()
+
+  paras := self.spanned()
+  first := paras[0]
+  last := paras[len(paras)-1]
+
+  selected := map[*WordAPI]bool{}
+  for _, word := range words {
+    selected[word] = true
+  }
+  for _, para := range paras {
+    para.remove(selected)
+  }
+
+  if first != last {
+    first.Join(last)
+  }
+
+  // emptied paragraphs other than the first are discarded by check
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    if e.Value.(*ParaAPI) == first {
+      self.node = e
+    }
+  }
+  self.anchor = nil
+  return true
+}
@@ -0,0 +1,97 @@
+package prose
+
+import (
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+)
+
+// typeParas types each line as a paragraph of words.
+func typeParas(doc *DocAPI, lines ...string) {
+  for i, line := range lines {
+    if i > 0 {
+      doc.Space()
+      doc.Return()
+    }
+    for j, word := range strings.Fields(line) {
+      if j > 0 {
+        doc.Space()
+      }
+      doc.Insert(word)
+    }
+  }
+}
+
+func countFlag(doc *DocAPI, flag uint64) int {
+  n := 0
+  for _, para := range doc.Paragraphs() {
+    for _, word := range para.Words() {
+      if word.Is(flag) {
+        n++
+      }
+    }
+  }
+  return n
+}
+
+// crash reopens a document without saving or closing it first.
+func crash(t *testing.T, path string) *DocAPI {
+  // the journal must look newer than the saved file
+  old := time.Now().Add(-time.Minute)
+  os.Chtimes(path, old, old)
+  doc, err := NewDoc(path, DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+  return doc
+}
+
+// emphasiseAll saves five paragraphs, then emphasises all nine words
+// through one selection.
+func emphasiseAll(t *testing.T) (*DocAPI, string) {
+  path := filepath.Join(t.TempDir(), "novel.prose")
+
+  doc, err := NewDoc(path, DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+  typeParas(doc, "one two", "three four", "five", "six seven", "eight nine")
+  if err := doc.Save(); err != nil {
+    t.Fatal(err)
+  }
+
+  doc.Seek(doc.Paragraphs()[0])
+  for doc.SelectRight() {
+  }
+  if n := len(doc.Selection()); n != 9 {
+    t.Fatalf("selected %d words, want 9", n)
+  }
+
+  doc.Emphasis()
+  if n := countFlag(doc, Emphasis); n != 9 {
+    t.Fatalf("emphasised %d words, want 9", n)
+  }
+  return doc, path
+}
+
+func TestSelectionUndoJournalled(t *testing.T) {
+  doc, path := emphasiseAll(t)
+  doc.Undo()
+  if n := countFlag(doc, Emphasis); n != 0 {
+    t.Fatalf("after undo %d emphasised words, want 0", n)
+  }
+  if n := countFlag(crash(t, path), Emphasis); n != 0 {
+    t.Errorf("undone emphasis came back after a crash: %d words", n)
+  }
+}
+
+func TestSelectionRedoJournalled(t *testing.T) {
+  doc, path := emphasiseAll(t)
+  doc.Undo()
+  doc.Redo()
+  if n := countFlag(crash(t, path), Emphasis); n != 9 {
+    t.Errorf("redone emphasis lost after a crash: %d words, want 9", n)
+  }
+}
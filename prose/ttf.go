@@ -0,0 +1,215 @@
+package prose
+
+import (
+  "encoding/binary"
+  "errors"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "unicode/utf16"
+)
+
+// ttfFont holds just enough of a TrueType file to lay out text and
+// embed the font in a PDF.
+type ttfFont struct {
+  data       []byte
+  name       string
+  unitsPerEm int
+  ascent     int
+  descent    int
+  capHeight  int
+  italic     float64
+  bbox       [4]int
+  advances   []int
+  glyphs     map[rune]uint16
+}
+
+var errTTF = errors.New("not a usable TrueType font")
+
+func loadTTF(path string) (*ttfFont, error) {
+
+  data, err := ioutil.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+
+  self := &ttfFont{data: data, glyphs: map[rune]uint16{}}
+
+  u16 := func(b []byte, off int) int {
+    if off+2 > len(b) {
+      return 0
+    }
+    return int(binary.BigEndian.Uint16(b[off:]))
+  }
+  i16 := func(b []byte, off int) int {
+    return int(int16(u16(b, off)))
+  }
+  u32 := func(b []byte, off int) int {
+    if off+4 > len(b) {
+      return 0
+    }
+    return int(binary.BigEndian.Uint32(b[off:]))
+  }
+
+  if len(data) < 12 {
+    return nil, errTTF
+  }
+
+  tables := map[string][]byte{}
+  for i := 0; i < u16(data, 4); i++ {
+    rec := 12 + i*16
+    if rec+16 > len(data) {
+      return nil, errTTF
+    }
+    off := u32(data, rec+8)
+    size := u32(data, rec+12)
+    if off+size > len(data) {
+      return nil, errTTF
+    }
+    tables[string(data[rec:rec+4])] = data[off : off+size]
+  }
+
+  for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "cmap", "glyf"} {
+    if _, ok := tables[tag]; !ok {
+      return nil, errTTF
+    }
+  }
+
+  head := tables["head"]
+  self.unitsPerEm = u16(head, 18)
+  if self.unitsPerEm == 0 {
+    return nil, errTTF
+  }
+  self.bbox = [4]int{i16(head, 36), i16(head, 38), i16(head, 40), i16(head, 42)}
+
+  hhea := tables["hhea"]
+  self.ascent = i16(hhea, 4)
+  self.descent = i16(hhea, 6)
+  self.capHeight = self.ascent * 7 / 10
+  if os2, ok := tables["OS/2"]; ok && u16(os2, 0) >= 2 {
+    self.capHeight = i16(os2, 88)
+  }
+  if post, ok := tables["post"]; ok {
+    self.italic = float64(int32(u32(post, 4))) / 65536
+  }
+
+  numGlyphs := u16(tables["maxp"], 4)
+  numMetrics := u16(hhea, 34)
+  hmtx := tables["hmtx"]
+  self.advances = make([]int, numGlyphs)
+  for g := 0; g < numGlyphs; g++ {
+    if g < numMetrics {
+      self.advances[g] = u16(hmtx, g*4)
+    } else if numMetrics > 0 {
+      self.advances[g] = self.advances[numMetrics-1]
+    }
+  }
+
+  // prefer a full Unicode cmap (3,10 format 12), then the BMP (3,1 / 0,x format 4)
+  cmap := tables["cmap"]
+  best := []byte(nil)
+  rank := 0
+  for i := 0; i < u16(cmap, 2); i++ {
+    rec := 4 + i*8
+    platform, encoding, off := u16(cmap, rec), u16(cmap, rec+2), u32(cmap, rec+4)
+    if off >= len(cmap) {
+      continue
+    }
+    sub := cmap[off:]
+    r := 0
+    switch {
+    case platform == 3 && encoding == 10 && u16(sub, 0) == 12:
+      r = 3
+    case platform == 3 && encoding == 1 && u16(sub, 0) == 4:
+      r = 2
+    case platform == 0 && (u16(sub, 0) == 4 || u16(sub, 0) == 12):
+      r = 1
+    }
+    if r > rank {
+      best, rank = sub, r
+    }
+  }
+  if best == nil {
+    return nil, errTTF
+  }
+
+  if u16(best, 0) == 12 {
+    for i := 0; i < u32(best, 12); i++ {
+      group := 16 + i*12
+      start, end, glyph := u32(best, group), u32(best, group+4), u32(best, group+8)
+      for c := start; c <= end && c-start < 0x10000; c++ {
+        self.glyphs[rune(c)] = uint16(glyph + c - start)
+      }
+    }
+  } else {
+    segs := u16(best, 6) / 2
+    ends, starts, deltas, ranges := 14, 16+segs*2, 16+segs*4, 16+segs*6
+    for s := 0; s < segs; s++ {
+      end, start := u16(best, ends+s*2), u16(best, starts+s*2)
+      delta, rangeOff := u16(best, deltas+s*2), u16(best, ranges+s*2)
+      for c := start; c <= end && c != 0xFFFF; c++ {
+        g := 0
+        if rangeOff == 0 {
+          g = (c + delta) & 0xFFFF
+        } else if g = u16(best, ranges+s*2+rangeOff+2*(c-start)); g != 0 {
+          g = (g + delta) & 0xFFFF
+        }
+        if g != 0 {
+          self.glyphs[rune(c)] = uint16(g)
+        }
+      }
+    }
+  }
+
+  // PostScript name from the name table, else the file name
+  self.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+  if name, ok := tables["name"]; ok {
+    store := u16(name, 4)
+    for i := 0; i < u16(name, 2); i++ {
+      rec := 6 + i*12
+      platform, id, size, off := u16(name, rec), u16(name, rec+6), u16(name, rec+8), u16(name, rec+10)
+      if id != 6 || store+off+size > len(name) {
+        continue
+      }
+      raw := name[store+off : store+off+size]
+      if platform == 3 || platform == 0 {
+        units := make([]uint16, len(raw)/2)
+        for j := range units {
+          units[j] = uint16(u16(raw, j*2))
+        }
+        self.name = string(utf16.Decode(units))
+      } else {
+        self.name = string(raw)
+      }
+      break
+    }
+  }
+  self.name = strings.Map(func(r rune) rune {
+    if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+      return -1
+    }
+    return r
+  }, self.name)
+
+  return self, nil
+}
+
+func (self *ttfFont) Glyph(r rune) uint16 {
+  return self.glyphs[r]
+}
+
+// Width of a string in points at the given size.
+func (self *ttfFont) Width(str string, size float64) float64 {
+  units := 0
+  for _, r := range str {
+    if g := int(self.Glyph(r)); g < len(self.advances) {
+      units += self.advances[g]
+    }
+  }
+  return float64(units) * size / float64(self.unitsPerEm)
+}
+
+// scale converts font units to the 1000-unit glyph space of PDF.
+func (self *ttfFont) scale(units int) int {
+  return units * 1000 / self.unitsPerEm
+}
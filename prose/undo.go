@@ -0,0 +1,296 @@
+package prose
+
+import (
+  "container/list"
+)
+
+const undoDepth = 1000
+
+type wordState struct {
+  text  string
+  flags uint64
+}
+
+type paraState struct {
+  para  *ParaAPI
+  style int
+  words []wordState
+  focus int
+}
+
+// docState is a memento of the document taken around an edit. The
+// paragraph order, cursor, variables and metadata are always captured;
+// word content only for the focused paragraph and its neighbours, which
+// is all any single DocAPI edit can touch, plus any paragraphs a
+// selection spans. States share one order slice until the order
+// changes, so typing costs no copy of it.
+type docState struct {
+  paras []*ParaAPI // shared, never modified
+  saved []paraState
+  focus *ParaAPI
+  vars  map[string]string
+  meta  map[string]string
+}
+
+type undoStep struct {
+  merge  interface{}
+  before *docState
+  after  *docState
+}
+
+type undoAPI struct {
+  list *list.List
+  node *list.Element
+}
+
+func newUndo() *undoAPI {
+  self := &undoAPI{}
+  self.list = list.New()
+  return self
+}
+
+// record pushes an edit, discarding anything that could have been
+// redone. Consecutive edits sharing a non-nil merge key (typing into
+// the same word) collapse into a single step.
+func (self *undoAPI) record(merge interface{}, before *docState, after *docState) {
+
+  if before.equals(after) {
+    return
+  }
+
+  for self.list.Back() != self.node {
+    self.list.Remove(self.list.Back())
+  }
+
+  if self.node != nil && merge != nil {
+    step := self.node.Value.(*undoStep)
+    if step.merge == merge {
+      step.after = after
+      return
+    }
+  }
+
+  self.node = self.list.PushBack(&undoStep{merge, before, after})
+
+  for self.list.Len() > undoDepth {
+    self.list.Remove(self.list.Front())
+  }
+}
+
+func (self *undoAPI) Undo() *docState {
+  if self.node == nil {
+    return nil
+  }
+  step := self.node.Value.(*undoStep)
+  step.merge = nil
+  self.node = self.node.Prev()
+  self.seal()
+  return step.before
+}
+
+func (self *undoAPI) Redo() *docState {
+  next := self.list.Front()
+  if self.node != nil {
+    next = self.node.Next()
+  }
+  if next == nil {
+    return nil
+  }
+  self.node = next
+  self.seal()
+  return next.Value.(*undoStep).after
+}
+
+// seal stops the current step absorbing further edits after the
+// cursor has travelled through history.
+func (self *undoAPI) seal() {
+  if self.node != nil {
+    self.node.Value.(*undoStep).merge = nil
+  }
+}
+
+func (self *ParaAPI) state() paraState {
+  state := paraState{
+    para:  self,
+    style: self.style,
+    words: []wordState{},
+  }
+  for e := self.list.Front(); e != nil; e = e.Next() {
+    word := e.Value.(*WordAPI)
+    if e == self.node {
+      state.focus = len(state.words)
+    }
+    state.words = append(state.words, wordState{word.text, word.flags})
+  }
+  return state
+}
+
+func (self *ParaAPI) restore(state paraState) {
+  defer self.check()
+
+  self.style = state.style
+  self.list = list.New()
+  self.node = nil
+
+  for i, ws := range state.words {
+    word := newWord(self)
+    word.text = ws.text
+    word.flags = ws.flags
+    e := self.list.PushBack(word)
+    if i == state.focus {
+      self.node = e
+    }
+  }
+}
+
+func (self paraState) equals(other paraState) bool {
+  if self.para != other.para || self.style != other.style || self.focus != other.focus {
+    return false
+  }
+  if len(self.words) != len(other.words) {
+    return false
+  }
+  for i := range self.words {
+    if self.words[i] != other.words[i] {
+      return false
+    }
+  }
+  return true
+}
+
+// reordered notes that paragraphs were added, removed or moved.
+func (self *DocAPI) reordered() {
+  self.order = nil
+}
+
+// paras returns the paragraphs in order, rebuilt only after a change.
+// The slice is shared with saved states, so must not be modified.
+func (self *DocAPI) paras() []*ParaAPI {
+  if self.order == nil {
+    self.order = []*ParaAPI{}
+    for e := self.list.Front(); e != nil; e = e.Next() {
+      self.order = append(self.order, e.Value.(*ParaAPI))
+    }
+  }
+  return self.order
+}
+
+func (self *DocAPI) state(spanned ...*ParaAPI) *docState {
+  state := &docState{
+    paras: self.paras(),
+    saved: []paraState{},
+    focus: self.Paragraph(),
+    vars:  copyMap(self.vars),
+    meta:  copyMap(self.meta),
+  }
+
+  if self.node.Prev() != nil {
+    state.saved = append(state.saved, self.node.Prev().Value.(*ParaAPI).state())
+  }
+  state.saved = append(state.saved, self.Paragraph().state())
+  if self.node.Next() != nil {
+    state.saved = append(state.saved, self.node.Next().Value.(*ParaAPI).state())
+  }
+
+  if len(spanned) == 0 {
+    return state
+  }
+
+  saved := map[*ParaAPI]bool{}
+  for _, ps := range state.saved {
+    saved[ps.para] = true
+  }
+  present := map[*ParaAPI]bool{}
+  for _, para := range state.paras {
+    present[para] = true
+  }
+  for _, para := range spanned {
+    if present[para] && !saved[para] {
+      state.saved = append(state.saved, para.state())
+      saved[para] = true
+    }
+  }
+  return state
+}
+
+func (self *DocAPI) restore(state *docState) {
+  defer self.check()
+  self.Deselect()
+
+  self.list = list.New()
+  self.node = nil
+
+  for _, para := range state.paras {
+    e := self.list.PushBack(para)
+    if para == state.focus {
+      self.node = e
+    }
+  }
+  self.order = state.paras
+
+  for _, ps := range state.saved {
+    ps.para.restore(ps)
+  }
+
+  self.vars = copyMap(state.vars)
+  self.meta = copyMap(state.meta)
+}
+
+func copyMap(m map[string]string) map[string]string {
+  dup := map[string]string{}
+  for key, val := range m {
+    dup[key] = val
+  }
+  return dup
+}
+
+func sameMap(a map[string]string, b map[string]string) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for key, val := range a {
+    if v, ok := b[key]; !ok || v != val {
+      return false
+    }
+  }
+  return true
+}
+
+func parasOf(saved []paraState) []*ParaAPI {
+  paras := []*ParaAPI{}
+  for _, ps := range saved {
+    paras = append(paras, ps.para)
+  }
+  return paras
+}
+
+// sameOrder compares paragraph orders, cheaply when they are one slice.
+func sameOrder(a []*ParaAPI, b []*ParaAPI) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  if len(a) == 0 || &a[0] == &b[0] {
+    return true
+  }
+  for i := range a {
+    if a[i] != b[i] {
+      return false
+    }
+  }
+  return true
+}
+
+func (self *docState) equals(other *docState) bool {
+  if self.focus != other.focus || !sameOrder(self.paras, other.paras) {
+    return false
+  }
+  if len(self.saved) != len(other.saved) {
+    return false
+  }
+  for i := range self.saved {
+    if !self.saved[i].equals(other.saved[i]) {
+      return false
+    }
+  }
+  return sameMap(self.vars, other.vars) && sameMap(self.meta, other.meta)
+}
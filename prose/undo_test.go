@@ -0,0 +1,141 @@
+package prose
+
+import (
+  "image"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+// snapshot describes everything an undo step may restore.
+func snapshot(doc *DocAPI) string {
+  return strings.Replace(lines(doc), "\n", " ", -1)
+}
+
+// distinct drops repeats, for steps such as Space that change nothing
+// a reader can see.
+func distinct(states []string) []string {
+  out := []string{}
+  for _, state := range states {
+    if len(out) == 0 || out[len(out)-1] != state {
+      out = append(out, state)
+    }
+  }
+  return out
+}
+
+func TestUndoRedoChain(t *testing.T) {
+  doc := Create(filepath.Join(t.TempDir(), "undo.prose"), DefaultOptions())
+
+  edits := []func(){
+    func() { doc.Insert("one") },
+    func() { doc.Space() },
+    func() { doc.Insert("two") },
+    func() { doc.Period() },
+    func() { doc.Space() },
+    func() { doc.Return() },
+    func() { doc.Insert("three") },
+    func() { doc.Heading() },
+    func() { doc.Set("name", "value") },
+    func() { doc.ShiftUp() },
+    func() { doc.Emphasis() },
+    func() { doc.Drop("name") },
+    func() { doc.SetMeta("title", "A title") },
+    func() { doc.SetMeta("title", "") },
+  }
+
+  states := []string{snapshot(doc)}
+  for _, edit := range edits {
+    edit()
+    states = append(states, snapshot(doc))
+  }
+  states = distinct(states)
+
+  undone := []string{snapshot(doc)}
+  for doc.Undo() {
+    undone = append(undone, snapshot(doc))
+  }
+  undone = distinct(undone)
+  for i := range undone {
+    if j := len(states) - 1 - i; j < 0 || undone[i] != states[j] {
+      t.Fatalf("undo %d gave %q\nwant %v", i, undone[i], states)
+    }
+  }
+  if len(undone) != len(states) {
+    t.Fatalf("undid %d states, want %d", len(undone), len(states))
+  }
+
+  redone := []string{snapshot(doc)}
+  for doc.Redo() {
+    redone = append(redone, snapshot(doc))
+  }
+  if got, want := strings.Join(distinct(redone), "\n"), strings.Join(states, "\n"); got != want {
+    t.Errorf("redo gave\n%s\nwant\n%s", got, want)
+  }
+
+  // a new edit after undoing discards what could have been redone
+  doc.Undo()
+  doc.Insert("x")
+  if doc.Redo() {
+    t.Error("redo after a new edit")
+  }
+}
+
+func TestUndoMergesTyping(t *testing.T) {
+  doc := Create(filepath.Join(t.TempDir(), "merge.prose"), DefaultOptions())
+  for _, s := range []string{"w", "o", "r", "d"} {
+    doc.Insert(s)
+  }
+  doc.Undo()
+  if got := doc.Paragraph().Plain(); got != "" {
+    t.Errorf("one undo left %q of a typed word", got)
+  }
+}
+
+// flat is a Layout with every word in one place, so Up and Down always
+// leave the paragraph.
+type flat struct{}
+
+func (flat) Word(word *WordAPI) image.Rectangle { return image.Rectangle{} }
+func (flat) LineHeight(para *ParaAPI) int        { return 10 }
+
+// The cached paragraph order must follow every change to the list.
+func TestParagraphOrder(t *testing.T) {
+  dir := t.TempDir()
+  md := filepath.Join(dir, "more.md")
+  ioutil.WriteFile(md, []byte("imported one\n\nimported two"), 0644)
+
+  doc, err := NewDoc(filepath.Join(dir, "order.prose"), DefaultOptions())
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  ops := []func(){
+    func() { typeParas(doc, "one", "two", "three") },
+    func() { doc.Up(flat{}) },
+    func() { doc.Up(flat{}) },
+    func() { doc.Up(flat{}) },
+    func() { doc.Up(flat{}) },
+    func() { doc.Insert("top") },
+    func() { doc.ShiftDown() },
+    func() { doc.Down(flat{}) },
+    func() { doc.ShiftUp() },
+    func() { doc.Import(md) },
+    func() { doc.Undo() },
+    func() { doc.Redo() },
+    func() { doc.Seek(doc.Paragraphs()[0]); for doc.SelectRight() {} },
+    func() { doc.BackSpace() },
+    func() { doc.Undo() },
+    func() { doc.Bottom(); doc.Down(flat{}); doc.Down(flat{}); doc.Down(flat{}) },
+    func() { doc.Paste(&Clip{paras: []*ParaAPI{newPara(doc), newPara(doc)}}) },
+    func() { doc.Delete() },
+  }
+
+  for i, op := range ops {
+    op()
+    if got, want := idList(doc.paras()), idList(doc.All()); !sameIds(got, want) {
+      t.Fatalf("after op %d cached order %v, list %v", i, got, want)
+    }
+  }
+}
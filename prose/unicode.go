@@ -0,0 +1,120 @@
+package prose
+
+import (
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+const zwj = '\u200d'
+
+func isRegional(r rune) bool {
+  return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// extends reports whether r continues the grapheme cluster before it,
+// following the rules of UAX #29 that matter for prose: combining marks,
+// joiners, variation selectors and skin tones attach to what precedes
+// them, and regional indicators pair into flags. regional is how many
+// regional indicators immediately precede r.
+func extends(prev rune, r rune, regional int) bool {
+  switch {
+  case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+    return true
+  case r == zwj || prev == zwj:
+    return true
+  case unicode.Is(unicode.Variation_Selector, r):
+    return true
+  case r >= 0x1f3fb && r <= 0x1f3ff:
+    return true
+  case isRegional(r):
+    return regional%2 == 1
+  }
+  return false
+}
+
+// boundaries returns the byte offsets where the grapheme clusters of s
+// begin, followed by len(s).
+func boundaries(s string) []int {
+  bounds := []int{}
+  prev := rune(-1)
+  regional := 0
+  for i, r := range s {
+    if prev < 0 || !extends(prev, r, regional) {
+      bounds = append(bounds, i)
+    }
+    if isRegional(r) {
+      regional++
+    } else {
+      regional = 0
+    }
+    prev = r
+  }
+  return append(bounds, len(s))
+}
+
+// Clusters counts what a reader would call characters, as WordAPI.Len
+// does.
+func Clusters(s string) int {
+  return len(boundaries(s)) - 1
+}
+
+// clusterBefore returns where the character before offset i begins.
+func clusterBefore(s string, i int) int {
+  start := 0
+  for _, b := range boundaries(s) {
+    if b >= i {
+      break
+    }
+    start = b
+  }
+  return start
+}
+
+// clusterAfter returns where the character at offset i ends.
+func clusterAfter(s string, i int) int {
+  for _, b := range boundaries(s) {
+    if b > i {
+      return b
+    }
+  }
+  return len(s)
+}
+
+// upperFirst capitalises the first letter of s, after any leading
+// punctuation, by the conventions of a language tag: Turkish and Azeri
+// dot their capital I, and Dutch capitalises the digraph IJ whole. A word
+// that starts with a digit, as 1st or 3am, is left alone.
+func upperFirst(s string, lang string) string {
+
+  lang = strings.ToLower(lang)
+  if i := strings.IndexAny(lang, "-_"); i >= 0 {
+    lang = lang[:i]
+  }
+
+  for i, r := range s {
+    if unicode.IsPunct(r) {
+      continue
+    }
+    if !unicode.IsLetter(r) {
+      return s
+    }
+
+    rest := s[i+utf8.RuneLen(r):]
+
+    if lang == "nl" && (r == 'i' || r == 'I') && strings.HasPrefix(rest, "j") {
+      return s[:i] + "IJ" + rest[1:]
+    }
+
+    switch lang {
+    case "tr":
+      r = unicode.TurkishCase.ToTitle(r)
+    case "az":
+      r = unicode.AzeriCase.ToTitle(r)
+    default:
+      r = unicode.ToTitle(r)
+    }
+    return s[:i] + string(r) + rest
+  }
+  return s
+}
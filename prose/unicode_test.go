@@ -0,0 +1,57 @@
+package prose
+
+import (
+  "testing"
+)
+
+func TestUpperFirst(t *testing.T) {
+  tests := []struct {
+    word string
+    lang string
+    want string
+  }{
+    {"hello", "", "Hello"},
+    {"1st", "", "1st"},
+    {"3am", "", "3am"},
+    {"'tis", "", "'Tis"},
+    {"“well", "", "“Well"},
+    {"...and", "", "...And"},
+    {"\u00e9t\u00e9", "", "\u00c9t\u00e9"},
+    {"e\u0301te\u0301", "", "E\u0301te\u0301"},
+    {"ijsland", "nl", "IJsland"},
+    {"ijsland", "nl-BE", "IJsland"},
+    {"ijsland", "en", "Ijsland"},
+    {"istanbul", "tr", "İstanbul"},
+    {"istanbul", "", "Istanbul"},
+    {"ǆemal", "", "ǅemal"},
+    {"", "", ""},
+    {"--", "", "--"},
+  }
+  for _, test := range tests {
+    if got := upperFirst(test.word, test.lang); got != test.want {
+      t.Errorf("upperFirst(%q, %q) = %q, want %q", test.word, test.lang, got, test.want)
+    }
+  }
+}
+
+// Clusters counts characters as a reader sees them, not runes: an
+// accent typed as a combining mark, a flag, an emoji sequence.
+func TestClusters(t *testing.T) {
+  tests := []struct {
+    str  string
+    want int
+  }{
+    {"word", 4},
+    {"caf\u00e9", 4},
+    {"cafe\u0301", 4},
+    {"\U0001f1ec\U0001f1e7\U0001f1eb\U0001f1f7", 2},
+    {"\U0001f469\u200d\U0001f4bb", 1},
+    {"\U0001f44d\U0001f3fd", 1},
+    {"", 0},
+  }
+  for _, test := range tests {
+    if got := Clusters(test.str); got != test.want {
+      t.Errorf("Clusters(%q) = %d, want %d", test.str, got, test.want)
+    }
+  }
+}
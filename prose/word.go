@@ -0,0 +1,391 @@
+package prose
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+// Word flags, for punctuation that follows a word and for quoting and
+// style that may run across several.
+const (
+  DQuote uint64 = 1 << iota
+  Period
+  Exclaim
+  Question
+  Comma
+  Ellipsis
+  Hyphen
+  Paren
+  Colon
+  SemiColon
+  Emphasis
+  Variable
+)
+
+// punctFlags are the marks that end a word, of which it has at most one.
+const punctFlags = Comma | Period | Ellipsis | Exclaim | Question | Hyphen | Colon | SemiColon
+
+// WordAPI is a word of text and the flags for its punctuation and style.
+type WordAPI struct {
+  para  *ParaAPI
+  text  string
+  flags uint64
+  back  int // caret position in bytes from the end, so zero appends
+}
+
+func newWord(para *ParaAPI) *WordAPI {
+  self := &WordAPI{}
+  self.para = para
+  return self
+}
+
+// Is reports whether every bit of flag is set.
+func (self *WordAPI) Is(flag uint64) bool {
+  return (self.flags & flag) == flag
+}
+
+// Set sets the bits of flag.
+func (self *WordAPI) Set(flag uint64) {
+  self.flags |= flag
+}
+
+// Clr clears the bits of flag.
+func (self *WordAPI) Clr(flag uint64) {
+  self.flags &^= flag
+}
+
+// Toggle flips the bits of flag and reports whether they are now set.
+func (self *WordAPI) Toggle(flag uint64) bool {
+  self.flags ^= flag
+  return self.Is(flag)
+}
+
+// IsEmpty reports whether the word has no text.
+func (self *WordAPI) IsEmpty() bool {
+  return self.Len() == 0
+}
+
+// Len counts characters as a reader would, not bytes or runes.
+func (self *WordAPI) Len() int {
+  return Clusters(self.text)
+}
+
+// Text returns the word as typed: a variable's name, not its value.
+func (self *WordAPI) Text() string {
+  return self.text
+}
+
+// Display returns the word as a reader sees it, with a variable replaced
+// by its value.
+func (self *WordAPI) Display() string {
+  if self.Is(Variable) && self.para != nil && self.para.doc != nil {
+    return self.para.doc.Get(self.text, self.text)
+  }
+  return self.text
+}
+
+// Format renders the word with its punctuation between prev and next,
+// as the editor draws it. showVar shows variables by name.
+func (self *WordAPI) Format(prev *WordAPI, next *WordAPI, showVar bool) string {
+
+  str := self.Display()
+  if showVar && self.IsVariable() {
+    str = self.Text()
+  }
+  if len(str) == 0 {
+    str = "_"
+  }
+
+  prefix, suffix, gap := self.Affixes(prev, next)
+  return prefix + str + suffix + gap
+}
+
+// Affixes returns the punctuation and quoting rendered around the word
+// text, and the gap that follows it.
+func (self *WordAPI) Affixes(prev *WordAPI, next *WordAPI) (string, string, string) {
+
+  prefix := ""
+  suffix := ""
+  gap := " "
+
+  if self.Is(Comma) {
+    suffix = ","
+  }
+
+  if self.Is(Period) {
+    suffix = "."
+  }
+
+  if self.Is(Ellipsis) {
+    suffix = "…"
+  }
+
+  if self.Is(Exclaim) {
+    suffix = "!"
+  }
+
+  if self.Is(Question) {
+    suffix = "?"
+  }
+
+  if self.Is(Colon) {
+    suffix = ":"
+  }
+
+  if self.Is(SemiColon) {
+    suffix = ";"
+  }
+
+  if self.Is(Hyphen) {
+    suffix = "-"
+    gap = ""
+  }
+
+  if self.Is(DQuote) {
+    if prev == nil || !prev.Is(DQuote) {
+      prefix = "\""
+    }
+    if next == nil || !next.Is(DQuote) {
+      suffix = suffix + "\""
+    }
+  }
+
+  if self.Is(Paren) {
+    if prev == nil || !prev.Is(Paren) {
+      prefix = "("
+    }
+    if next == nil || !next.Is(Paren) {
+      suffix = suffix + ")"
+    }
+  }
+
+  return prefix, suffix, gap
+}
+
+// Export encodes the word for the document file as flags,text.
+func (self *WordAPI) Export(prev *WordAPI, next *WordAPI) string {
+  return fmt.Sprintf("%v,%s",
+    self.flags,
+    escape(self.text),
+  )
+}
+
+// Import decodes a word written by Export.
+func (self *WordAPI) Import(line string) {
+  fields := strings.SplitN(line, ",", 2)
+  self.flags, _ = strconv.ParseUint(fields[0], 10, 64)
+  if len(fields) > 1 {
+    self.text = unescape(strings.TrimSpace(fields[1]))
+  }
+}
+
+// Smart carries an open quotation on from the word before.
+func (self *WordAPI) Smart(prev *WordAPI, next *WordAPI) {
+  if prev != nil && prev.Is(DQuote) {
+    self.Set(DQuote)
+  }
+}
+
+// Caret returns the byte offset in Text of the caret, which always sits
+// between characters.
+func (self *WordAPI) Caret() int {
+  if self.back > len(self.text) {
+    self.back = len(self.text)
+  }
+  caret := len(self.text) - self.back
+  if caret > 0 && caret < len(self.text) {
+    caret = clusterBefore(self.text, caret+1)
+    self.back = len(self.text) - caret
+  }
+  return caret
+}
+
+// IsCaretEnd reports whether the caret is after the last character.
+func (self *WordAPI) IsCaretEnd() bool {
+  return self.Caret() == len(self.text)
+}
+
+// CaretLeft moves the caret back a character, reporting false at the
+// start.
+func (self *WordAPI) CaretLeft() bool {
+  if caret := self.Caret(); caret > 0 {
+    self.back = len(self.text) - clusterBefore(self.text, caret)
+    return true
+  }
+  return false
+}
+
+// CaretRight moves the caret on a character, reporting false at the end.
+func (self *WordAPI) CaretRight() bool {
+  if caret := self.Caret(); caret < len(self.text) {
+    self.back = len(self.text) - clusterAfter(self.text, caret)
+    return true
+  }
+  return false
+}
+
+// CaretEnd puts the caret after the last character.
+func (self *WordAPI) CaretEnd() {
+  self.back = 0
+}
+
+// Insert adds str at the caret, which stays after it.
+func (self *WordAPI) Insert(str string) {
+  caret := self.Caret()
+  self.text = self.text[:caret] + str + self.text[caret:]
+}
+
+// BackSpace deletes the character before the caret.
+func (self *WordAPI) BackSpace() bool {
+  caret := self.Caret()
+  if caret > 0 {
+    self.text = self.text[:clusterBefore(self.text, caret)] + self.text[caret:]
+    return true
+  }
+  return false
+}
+
+// Delete deletes the character after the caret.
+func (self *WordAPI) Delete() bool {
+  caret := self.Caret()
+  if caret < len(self.text) {
+    end := clusterAfter(self.text, caret)
+    self.text = self.text[:caret] + self.text[end:]
+    self.back -= end - caret
+    return true
+  }
+  return false
+}
+
+// Join appends next's text, with the caret left between the two, as
+// deleting the gap between words would. The word ends with next's
+// punctuation.
+func (self *WordAPI) Join(next *WordAPI) {
+  self.text += next.text
+  self.back = len(next.text)
+  self.flags = self.flags&^punctFlags | next.flags&punctFlags
+}
+
+// Clear deletes the whole text.
+func (self *WordAPI) Clear() bool {
+  self.back = 0
+  if len(self.text) > 0 {
+    self.text = ""
+    return true
+  }
+  return false
+}
+
+// DQuote toggles double quotation marks.
+func (self *WordAPI) DQuote() {
+  self.Toggle(DQuote)
+}
+
+// IsDQuote reports whether the word is quoted.
+func (self *WordAPI) IsDQuote() bool {
+  return self.Is(DQuote)
+}
+
+// Paren toggles parentheses.
+func (self *WordAPI) Paren() {
+  self.Toggle(Paren)
+}
+
+// IsParen reports whether the word is in parentheses.
+func (self *WordAPI) IsParen() bool {
+  return self.Is(Paren)
+}
+
+// Emphasis toggles emphasis.
+func (self *WordAPI) Emphasis() {
+  self.Toggle(Emphasis)
+}
+
+// IsEmphasis reports whether the word is emphasised.
+func (self *WordAPI) IsEmphasis() bool {
+  return self.Is(Emphasis)
+}
+
+// Variable toggles whether the word names a document variable.
+func (self *WordAPI) Variable() {
+  self.Toggle(Variable)
+}
+
+// IsVariable reports whether the word names a document variable.
+func (self *WordAPI) IsVariable() bool {
+  return self.Is(Variable)
+}
+
+// ClearPunct does nothing; punctuation is cleared by TogglePunct.
+func (self *WordAPI) ClearPunct() {
+}
+
+// TogglePunct toggles one punctuation mark, replacing any other.
+func (self *WordAPI) TogglePunct(flag uint64) {
+  if self.Toggle(flag) {
+    self.Clr(punctFlags &^ flag)
+  }
+}
+
+// Period toggles a full stop.
+func (self *WordAPI) Period() {
+  if self.Is(Period | Ellipsis) {
+    self.Ellipsis()
+    return
+  }
+  self.TogglePunct(Period)
+}
+
+// Ellipsis toggles an ellipsis.
+func (self *WordAPI) Ellipsis() {
+  self.TogglePunct(Ellipsis)
+}
+
+// Comma toggles a comma.
+func (self *WordAPI) Comma() {
+  self.TogglePunct(Comma)
+}
+
+// Exclaim toggles an exclamation mark.
+func (self *WordAPI) Exclaim() {
+  self.TogglePunct(Exclaim)
+}
+
+// Question toggles a question mark.
+func (self *WordAPI) Question() {
+  self.TogglePunct(Question)
+}
+
+// Hyphen toggles a hyphen, which joins the word to the next.
+func (self *WordAPI) Hyphen() {
+  self.TogglePunct(Hyphen)
+}
+
+// Colon toggles a colon.
+func (self *WordAPI) Colon() {
+  self.TogglePunct(Colon)
+}
+
+// SemiColon toggles a semicolon.
+func (self *WordAPI) SemiColon() {
+  self.TogglePunct(SemiColon)
+}
+
+// UCFirst capitalises the word's first letter in the document's
+// language, if its metadata gives one.
+func (self *WordAPI) UCFirst() {
+  if self.Is(Variable) {
+    return
+  }
+  lang := ""
+  if self.para != nil && self.para.doc != nil {
+    lang = self.para.doc.Meta("language")
+  }
+  self.text = upperFirst(self.text, lang)
+}
+
+// Reparent moves the word into para's keeping.
+func (self *WordAPI) Reparent(para *ParaAPI) {
+  self.para = para
+}
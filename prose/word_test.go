@@ -0,0 +1,116 @@
+package prose
+
+import (
+  "testing"
+)
+
+// caret shows a word's text with a bar at the caret.
+func caret(word *WordAPI) string {
+  return word.text[:word.Caret()] + "|" + word.text[word.Caret():]
+}
+
+// Each op is a character: < and > move the caret, b and d delete, and i
+// inserts the rest of the ops as text.
+func TestWordCaret(t *testing.T) {
+  tests := []struct {
+    text string
+    ops  string
+    want string
+  }{
+    {"hello", "<", "hell|o"},
+    {"hello", "<<<<<<", "|hello"},
+    {"hello", ">", "hello|"},
+    {"hello", "<<d", "hel|o"},
+    {"hello", "<b", "hel|o"},
+    {"hello", "d", "hello|"},
+    {"hello", "<<<<<b", "|hello"},
+    {"hello", "<<iX", "helX|lo"},
+    {"caf\u00e9", "<", "caf|\u00e9"},
+    {"caf\u00e9", "<d", "caf|"},
+    {"caf\u00e9", "b", "caf|"},
+    {"cafe\u0301", "<", "caf|e\u0301"},
+    {"cafe\u0301", "b", "caf|"},
+    {"cafe\u0301", "<<>", "caf|e\u0301"},
+    {"\U0001f1ec\U0001f1e7x", "<<", "|\U0001f1ec\U0001f1e7x"},
+    {"\U0001f1ec\U0001f1e7x", "bb", "|"},
+    {"\U0001f1ec\U0001f1e7x", "<<d", "|x"},
+    {"\u4e2d\u6587", "<iX", "\u4e2dX|\u6587"},
+  }
+  for _, test := range tests {
+    word := newWord(nil)
+    word.text = test.text
+    for i, op := range test.ops {
+      switch op {
+      case '<':
+        word.CaretLeft()
+      case '>':
+        word.CaretRight()
+      case 'b':
+        word.BackSpace()
+      case 'd':
+        word.Delete()
+      case 'i':
+        word.Insert(test.ops[i+1:])
+      }
+      if op == 'i' {
+        break
+      }
+    }
+    if got := caret(word); got != test.want {
+      t.Errorf("%q after %s = %q, want %q", test.text, test.ops, got, test.want)
+    }
+  }
+}
+
+func TestWordCaretEnds(t *testing.T) {
+  word := newWord(nil)
+  word.text = "ab"
+  if word.CaretRight() || word.Delete() {
+    t.Error("moved or deleted past the end")
+  }
+  word.CaretLeft()
+  word.CaretLeft()
+  if word.CaretLeft() || word.BackSpace() {
+    t.Error("moved or deleted before the start")
+  }
+}
+
+// At the end of a word Delete takes out the gap, joining the next word
+// on, rather than wiping the word.
+func TestDeleteJoinsWords(t *testing.T) {
+  doc := Create("", DefaultOptions())
+  typeParas(doc, "hello world")
+  doc.Left()
+  doc.Comma()
+  doc.Right()
+  doc.Period()
+  doc.Left()
+
+  doc.Delete()
+  words := doc.Paragraph().Words()
+  if len(words) != 1 || words[0].Text() != "helloworld" || !words[0].Is(Period) || words[0].Is(Comma) {
+    t.Fatalf("joined %q", doc.Paragraph().Plain())
+  }
+  if got := caret(words[0]); got != "hello|world" {
+    t.Errorf("caret %q", got)
+  }
+
+  doc.Delete()
+  if got := doc.Paragraph().Plain(); got != "helloorld." {
+    t.Errorf("deleting after the join gave %q", got)
+  }
+
+  doc.Undo()
+  doc.Undo()
+  if got := doc.Paragraph().Plain(); got != "hello, world." {
+    t.Errorf("undo gave %q", got)
+  }
+
+  // nothing after the last word of the last paragraph
+  doc.Right()
+  before := doc.Paragraph().Plain()
+  doc.Delete()
+  if got := doc.Paragraph().Plain(); got != before {
+    t.Errorf("delete at the very end gave %q", got)
+  }
+}
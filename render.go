@@ -0,0 +1,147 @@
+package main
+
+import (
+  "github.com/seanpringle/go-sdl2/sdl"
+  "github.com/seanpringle/gostuff/box"
+  "image"
+  "image/color"
+  "image/draw"
+  "sort"
+  "unsafe"
+)
+
+// renderer composites a frame of sprites onto some surface.
+type renderer interface {
+  Clear()
+  Draw(s *sprite)
+  Present()
+}
+
+// composite draws a frame bottom layer first, keeping the order sprites
+// were emitted in within each layer. It sorts a copy, as the sprites may
+// belong to a layout that is still in use.
+func composite(r renderer, sprites []*sprite) {
+  sprites = append([]*sprite{}, sprites...)
+  sort.SliceStable(sprites, func(i, j int) bool {
+    return sprites[i].layer < sprites[j].layer
+  })
+  r.Clear()
+  for _, s := range sprites {
+    r.Draw(s)
+  }
+  r.Present()
+}
+
+// imageRenderer composites offscreen, for snapshots. Sprites are copied
+// unscaled and unrotated.
+type imageRenderer struct {
+  img *image.RGBA
+}
+
+func newImageRenderer(w, h int) *imageRenderer {
+  return &imageRenderer{img: image.NewRGBA(image.Rect(0, 0, w, h))}
+}
+
+func (self *imageRenderer) Clear() {
+  draw.Draw(self.img, self.img.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+}
+
+func (self *imageRenderer) Draw(s *sprite) {
+  dst := image.Rect(s.dst.X, s.dst.Y, s.dst.X+s.dst.W, s.dst.Y+s.dst.H)
+  draw.Draw(self.img, dst, s.rgba, s.rgba.Bounds().Min.Add(image.Pt(s.src.X, s.src.Y)), draw.Over)
+}
+
+func (self *imageRenderer) Present() {
+}
+
+// sdlRenderer batches sprites into one copy per frame, keeping textures
+// for cached images between frames. Use it only within sdl.Do.
+type sdlRenderer struct {
+  renderer *sdl.Renderer
+  masks    [4]uint32 // r, g, b, a
+  cache    map[*image.RGBA]*sdl.Texture
+  textures []*sdl.Texture
+  srects   []*sdl.Rect
+  drects   []*sdl.Rect
+  angles   []float64
+  uncache  []*image.RGBA
+}
+
+func boxSDL(self box.Box) sdl.Rect {
+  return sdl.Rect{int32(self.X), int32(self.Y), int32(self.W), int32(self.H)}
+}
+
+func newSDLRenderer(renderer *sdl.Renderer) *sdlRenderer {
+  self := &sdlRenderer{renderer: renderer}
+  self.cache = map[*image.RGBA]*sdl.Texture{}
+  if sdl.BYTEORDER == sdl.BIG_ENDIAN {
+    self.masks = [4]uint32{0xff000000, 0x00ff0000, 0x0000ff00, 0x000000ff}
+  } else {
+    self.masks = [4]uint32{0x000000ff, 0x0000ff00, 0x00ff0000, 0xff000000}
+  }
+  return self
+}
+
+func (self *sdlRenderer) texture(rgba *image.RGBA) *sdl.Texture {
+  if texture, ok := self.cache[rgba]; ok {
+    return texture
+  }
+
+  rect := rgba.Bounds()
+  surface, err := sdl.CreateRGBSurfaceFrom(
+    unsafe.Pointer(&rgba.Pix[0]),
+    rect.Dx(),
+    rect.Dy(),
+    32,
+    rect.Dx()*4,
+    self.masks[0],
+    self.masks[1],
+    self.masks[2],
+    self.masks[3],
+  )
+  if err != nil {
+    panic(err)
+  }
+
+  texture, err := self.renderer.CreateTextureFromSurface(surface)
+  if err != nil {
+    panic(err)
+  }
+
+  surface.Free()
+  self.cache[rgba] = texture
+  return texture
+}
+
+func (self *sdlRenderer) Clear() {
+  self.renderer.SetDrawColor(0, 0, 0, 0)
+  self.renderer.Clear()
+  self.textures = self.textures[:0]
+  self.srects = self.srects[:0]
+  self.drects = self.drects[:0]
+  self.angles = self.angles[:0]
+  self.uncache = self.uncache[:0]
+}
+
+func (self *sdlRenderer) Draw(s *sprite) {
+  src := boxSDL(s.src)
+  dst := boxSDL(s.dst)
+  self.textures = append(self.textures, self.texture(s.rgba))
+  self.srects = append(self.srects, &src)
+  self.drects = append(self.drects, &dst)
+  self.angles = append(self.angles, s.angle)
+  if !s.cache {
+    self.uncache = append(self.uncache, s.rgba)
+  }
+}
+
+func (self *sdlRenderer) Present() {
+  self.renderer.CopyBatch(self.textures, self.srects, self.drects, self.angles)
+  for _, rgba := range self.uncache {
+    if texture, ok := self.cache[rgba]; ok {
+      texture.Destroy()
+      delete(self.cache, rgba)
+    }
+  }
+  self.renderer.Present()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+  "testing"
+)
+
+// recorder is a renderer that notes the order sprites are drawn in.
+type recorder struct {
+  drawn []*sprite
+}
+
+func (self *recorder) Clear()         { self.drawn = nil }
+func (self *recorder) Draw(s *sprite) { self.drawn = append(self.drawn, s) }
+func (self *recorder) Present()       {}
+
+func TestCompositeOrder(t *testing.T) {
+  top := &sprite{layer: Overlay}
+  middle := &sprite{layer: Document}
+  bottom := &sprite{layer: BackGround}
+  also := &sprite{layer: Document}
+
+  sprites := []*sprite{top, middle, bottom, also}
+  r := &recorder{}
+  composite(r, sprites)
+
+  want := []*sprite{bottom, middle, also, top}
+  for i := range want {
+    if r.drawn[i] != want[i] {
+      t.Fatalf("sprite %d drawn on layer %d, want layer %d", i, r.drawn[i].layer, want[i].layer)
+    }
+  }
+
+  // the caller's slice, perhaps a layout's, is left as it was
+  for i, s := range []*sprite{top, middle, bottom, also} {
+    if sprites[i] != s {
+      t.Fatalf("composite reordered its argument at %d", i)
+    }
+  }
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+  "github.com/seanpringle/gostuff/box"
+  "github.com/seanpringle/gostuff/text"
+  "github.com/seanpringle/prose/prose"
+  "image"
+  "image/color"
+  "image/draw"
+)
+
+// layoutAPI records where a frame drew each word, and the sprites that
+// drew them. It is built afresh by every draw, never changed afterwards,
+// and implements prose.Layout for cursor movement by line.
+type layoutAPI struct {
+  words    map[*prose.WordAPI]box.Box
+  heights  map[*prose.ParaAPI]int
+  selected map[*prose.WordAPI]bool
+  sprites  []*sprite
+}
+
+func newLayout() *layoutAPI {
+  self := &layoutAPI{}
+  self.words = map[*prose.WordAPI]box.Box{}
+  self.heights = map[*prose.ParaAPI]int{}
+  self.selected = map[*prose.WordAPI]bool{}
+  return self
+}
+
+func (self *layoutAPI) Word(word *prose.WordAPI) image.Rectangle {
+  pos := self.words[word]
+  return image.Rect(pos.X, pos.Y, pos.X+pos.W, pos.Y+pos.H)
+}
+
+func (self *layoutAPI) LineHeight(para *prose.ParaAPI) int {
+  rgba := text.DrawCache(Light, prose.FontSizes[para.Style()], "Jj")
+  return rgba.Bounds().Dy()
+}
+
+func (self *layoutAPI) Height(para *prose.ParaAPI) int {
+  return self.heights[para]
+}
+
+// drawDoc lays the document out around the focused paragraph, placing
+// paragraphs above by the heights they had in the previous frame. Text an
+// input method is composing is shown inside the focused word. It reads
+// the document, so call it only from the goroutine that edits it.
+func drawDoc(doc *prose.DocAPI, prev *layoutAPI, view box.Box, composing string) *layoutAPI {
+
+  self := newLayout()
+  for _, word := range doc.Selection() {
+    self.selected[word] = true
+  }
+
+  paraSpacing := func(lineHeight int) int {
+    return int(float64(lineHeight) * 1.5)
+  }
+
+  view = view.Grow(-300, -100)
+
+  paras := doc.All()
+  focus := doc.Paragraph()
+  index := 0
+  for i, para := range paras {
+    if para == focus {
+      index = i
+    }
+  }
+
+  fpos := view.Translate(0, (view.H-prev.Height(focus))/2)
+  cpos := self.drawPara(focus, true, composing, fpos, view)
+
+  upos := fpos
+  dpos := cpos
+
+  for i := index - 1; i >= 0; i-- {
+    para := paras[i]
+    upos.X = view.X
+    upos.Y -= prev.Height(para) + paraSpacing(self.LineHeight(para))
+    if upos.Y < view.Y+view.H {
+      self.drawPara(para, false, "", upos, view)
+    }
+  }
+
+  dpos.Y += paraSpacing(self.LineHeight(focus))
+
+  for i := index + 1; i < len(paras); i++ {
+    para := paras[i]
+    dpos.X = view.X
+    if dpos.Y < view.Y+view.H {
+      self.drawPara(para, false, "", dpos, view)
+      dpos.Y += self.Height(para) + paraSpacing(self.LineHeight(para))
+    }
+  }
+
+  return self
+}
+
+func (self *layoutAPI) drawPara(para *prose.ParaAPI, focus bool, composing string, pos box.Box, view box.Box) box.Box {
+
+  x := pos.X
+  y := pos.Y
+  style := para.Style()
+
+  lineSpacing := func() int {
+    return int(float64(self.LineHeight(para)) * 1.2)
+  }
+
+  // emitText places images side by side, wrapping before them as one
+  emitText := func(cache bool, parts ...*image.RGBA) box.Box {
+
+    width, height := 0, 0
+    for _, rgba := range parts {
+      rect := rgba.Bounds()
+      width += rect.Dx()
+      if rect.Dy() > height {
+        height = rect.Dy()
+      }
+    }
+
+    if pos.X+width > view.X+view.W {
+      pos.X = x
+      pos.Y += lineSpacing()
+    }
+
+    dst := box.Box{pos.X, pos.Y, width, height}
+
+    for _, rgba := range parts {
+      rect := rgba.Bounds()
+      self.sprites = append(self.sprites, &sprite{
+        rgba:  rgba,
+        layer: Document,
+        src:   box.Box{0, 0, rect.Dx(), rect.Dy()},
+        dst:   box.Box{pos.X, pos.Y, rect.Dx(), rect.Dy()},
+        cache: cache,
+      })
+      pos = pos.Translate(rect.Dx(), 0)
+    }
+    return dst
+  }
+
+  if style == prose.Bullet {
+    emitText(true, text.DrawCache(prose.FontColors[prose.Bullet], prose.FontSizes[style], "• "))
+  }
+
+  words := para.All()
+  cursor := para.Word()
+
+  for i, word := range words {
+
+    prev := (*prose.WordAPI)(nil)
+    next := (*prose.WordAPI)(nil)
+    if i > 0 {
+      prev = words[i-1]
+    }
+    if i < len(words)-1 {
+      next = words[i+1]
+    }
+
+    color := prose.FontColors[style]
+
+    if word.IsDQuote() {
+      color = prose.FontColors[prose.Quote]
+    }
+
+    if word.IsParen() {
+      color = prose.FontColors[prose.Comment]
+    }
+
+    if word.IsEmphasis() {
+      color = prose.FontColors[prose.Highlight]
+    }
+
+    if focus && word == cursor {
+      color = prose.FontColors[prose.Focus]
+    }
+
+    showVar := focus && word.IsVariable() && word == cursor
+
+    // the focused word is split at its caret when that is not at the end,
+    // or to show text being composed there
+    if focus && word == cursor && (len(composing) > 0 || !word.IsCaretEnd()) {
+      str := word.Text()
+      caret := word.Caret()
+      prefix, suffix, gap := word.Affixes(prev, next)
+      before := prefix + str[:caret]
+      after := str[caret:] + suffix + gap
+
+      parts := []*image.RGBA{}
+      if len(before) > 0 {
+        parts = append(parts, text.Draw(color, prose.FontSizes[style], before))
+      }
+      if len(composing) > 0 {
+        parts = append(parts, text.Draw(prose.FontColors[prose.Highlight], prose.FontSizes[style], composing))
+      } else {
+        parts = append(parts, block(prose.FontColors[prose.Highlight], 2, self.LineHeight(para)))
+      }
+      if len(after) > 0 {
+        parts = append(parts, text.Draw(color, prose.FontSizes[style], after))
+      }
+
+      self.words[word] = emitText(false, parts...)
+      continue
+    }
+
+    wordStr := word.Format(prev, next, showVar)
+
+    self.words[word] = emitText(true, text.DrawCache(color, prose.FontSizes[style], wordStr))
+  }
+
+  // selected words are shaded from behind
+  for _, word := range words {
+    if self.selected[word] {
+      pos := self.words[word]
+      self.sprites = append(self.sprites, &sprite{
+        rgba:  block(Shade, pos.W, pos.H),
+        layer: BackGround,
+        src:   box.Box{0, 0, pos.W, pos.H},
+        dst:   pos,
+      })
+    }
+  }
+
+  self.heights[para] = pos.Y - y
+
+  return pos
+}
+
+// block is a solid rectangle, for the caret and selection shading.
+func block(c color.Color, w, h int) *image.RGBA {
+  rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+  draw.Draw(rgba, rgba.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+  return rgba
+}
+
+// snapshot renders a document offscreen as the editor would show it in
+// a window of the given size.
+func snapshot(doc *prose.DocAPI, w, h int) *image.RGBA {
+  screen := newImageRenderer(w, h)
+  composite(screen, snapshotLayout(doc, w, h).sprites)
+  return screen.img
+}
+
+// snapshotLayout lays a document out as snapshot draws it.
+func snapshotLayout(doc *prose.DocAPI, w, h int) *layoutAPI {
+  view := box.Box{0, 0, w, h}
+  layout := newLayout()
+
+  // the first pass only measures the paragraphs above the cursor
+  for pass := 0; pass < 2; pass++ {
+    layout = drawDoc(doc, layout, view, "")
+  }
+  return layout
+}
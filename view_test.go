@@ -0,0 +1,114 @@
+package main
+
+import (
+  "bytes"
+  "github.com/seanpringle/gostuff/box"
+  "github.com/seanpringle/prose/prose"
+  "image"
+  "image/png"
+  "path/filepath"
+  "testing"
+)
+
+// fixedDoc is a small document whose layout should never change by
+// accident: a heading, a paragraph long enough to wrap, and a bullet.
+func fixedDoc(t *testing.T) *prose.DocAPI {
+  doc := prose.Create(filepath.Join(t.TempDir(), "fixed.prose"), prose.DefaultOptions())
+  doc.Insert("Heading")
+  doc.Heading()
+  doc.Space()
+  doc.Return()
+  for i := 0; i < 12; i++ {
+    for j, word := range []string{"Some", "plain", "words", "then", "emphasis"} {
+      if i > 0 || j > 0 {
+        doc.Space()
+      }
+      doc.Insert(word)
+    }
+  }
+  doc.Emphasis()
+  doc.Period()
+  doc.Space()
+  doc.Return()
+  doc.Insert("listed")
+  doc.Bullet()
+  doc.Seek(doc.Paragraphs()[0])
+  return doc
+}
+
+func encode(t *testing.T, img image.Image) []byte {
+  var buf bytes.Buffer
+  if err := png.Encode(&buf, img); err != nil {
+    t.Fatal(err)
+  }
+  return buf.Bytes()
+}
+
+func TestSnapshotStable(t *testing.T) {
+  doc := fixedDoc(t)
+  if !bytes.Equal(encode(t, snapshot(doc, 1200, 800)), encode(t, snapshot(doc, 1200, 800))) {
+    t.Fatal("two snapshots of one document differ")
+  }
+}
+
+// The layout is checked by where words land relative to each other and
+// to the view, which holds whatever font is installed.
+func TestSnapshotLayout(t *testing.T) {
+  doc := fixedDoc(t)
+  w, h := 1200, 800
+  layout := snapshotLayout(doc, w, h)
+  view := box.Box{0, 0, w, h}.Grow(-300, -100)
+  left, right := view.X, view.X+view.W
+
+  bottom := 0
+  for i, para := range doc.Paragraphs() {
+    words := para.Words()
+    lines := 1
+    top := 0
+
+    for j, word := range words {
+      r := layout.Word(word)
+      if r.Empty() {
+        t.Fatalf("paragraph %d word %q was not drawn", i, word.Text())
+      }
+      if j == 0 {
+        top = r.Min.Y
+        if i > 0 && top <= bottom {
+          t.Errorf("paragraph %d starts at %d, above the one before ending at %d", i, top, bottom)
+        }
+      }
+      if r.Max.X > right || (r.Min.X < left && para.Style() != prose.Bullet) {
+        t.Errorf("paragraph %d word %q at %v is outside %d-%d", i, word.Text(), r, left, right)
+      }
+      if j > 0 {
+        prev := layout.Word(words[j-1])
+        switch {
+        case r.Min.Y == prev.Min.Y && r.Min.X >= prev.Max.X:
+        case r.Min.Y > prev.Min.Y && r.Min.X < prev.Min.X:
+          lines++
+        default:
+          t.Errorf("paragraph %d word %q at %v does not follow %v", i, word.Text(), r, prev)
+        }
+      }
+      if r.Max.Y > bottom {
+        bottom = r.Max.Y
+      }
+    }
+
+    if got := layout.Height(para); got < 0 || (lines > 1 && got == 0) {
+      t.Errorf("paragraph %d has height %d over %d lines", i, got, lines)
+    }
+    if i == 1 && lines < 2 {
+      t.Errorf("the long paragraph did not wrap")
+    }
+  }
+
+  again := snapshotLayout(doc, w, h)
+  for _, para := range doc.Paragraphs() {
+    for _, word := range para.Words() {
+      if layout.Word(word) != again.Word(word) {
+        t.Fatalf("word %q moved between layouts", word.Text())
+      }
+    }
+  }
+}